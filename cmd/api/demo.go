@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"cryptorate-service/internal/models"
+	"cryptorate-service/internal/repository"
+)
+
+// Демо-режим (--demo): API работает без PostgreSQL на MemoryRepository
+// с синтетическими курсами. Цены - случайное блуждание от начальных значений,
+// история заполняется за demoHistory, дальше курс добавляется каждые demoStep.
+const (
+	demoHistory = 7 * 24 * time.Hour
+	demoStep    = 5 * time.Minute
+	demoSource  = "demo"
+	// demoVolatility стандартное отклонение изменения цены за шаг
+	demoVolatility = 0.001
+)
+
+// demoCurrencies валюты демо-режима, те же, что добавляет миграция 0001_init
+var demoCurrencies = []struct {
+	name, displayName, symbol string
+	price                     float64
+}{
+	{"bitcoin", "Bitcoin", "BTC", 45000},
+	{"ethereum", "Ethereum", "ETH", 2500},
+	{"tether", "Tether", "USDT", 1},
+	{"binancecoin", "BNB", "BNB", 300},
+	{"solana", "Solana", "SOL", 100},
+	{"ripple", "XRP", "XRP", 0.6},
+	{"cardano", "Cardano", "ADA", 0.5},
+}
+
+// demoQuotes курсы валют котировки к доллару
+var demoQuotes = map[string]float64{
+	"usd": 1,
+	"eur": 0.92,
+	"rub": 92,
+}
+
+// demoFeed генерирует синтетические курсы для демо-режима
+type demoFeed struct {
+	repo   *repository.MemoryRepository
+	rng    *rand.Rand
+	prices map[int]float64 // текущая цена в долларах по ID валюты
+}
+
+// newDemoFeed заполняет repo валютами и историей курсов до now
+func newDemoFeed(ctx context.Context, repo *repository.MemoryRepository, now time.Time) (*demoFeed, error) {
+	feed := &demoFeed{
+		repo:   repo,
+		rng:    rand.New(rand.NewSource(now.UnixNano())),
+		prices: make(map[int]float64),
+	}
+
+	for _, c := range demoCurrencies {
+		currency, err := repo.CreateCurrency(ctx, models.Currency{
+			NameCurrency: c.name, DisplayName: c.displayName, Symbol: c.symbol, IsTracked: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		feed.prices[currency.ID] = c.price
+
+		history := make(map[string][]models.PricePoint, len(demoQuotes))
+		for t := now.Add(-demoHistory); !t.After(now); t = t.Add(demoStep) {
+			price := feed.step(currency.ID)
+			for quote, rate := range demoQuotes {
+				history[quote] = append(history[quote], models.PricePoint{Time: t, Price: demoDecimal(price * rate)})
+			}
+		}
+		for quote, points := range history {
+			if _, err := repo.SaveHistoricalRates(ctx, currency.ID, quote, demoSource, points); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return feed, nil
+}
+
+// step сдвигает цену валюты на случайную величину и возвращает новую цену
+func (f *demoFeed) step(currencyID int) float64 {
+	price := f.prices[currencyID] * (1 + f.rng.NormFloat64()*demoVolatility)
+	f.prices[currencyID] = price
+	return price
+}
+
+// Run добавляет курсы всех демо-валют каждые demoStep до отмены ctx,
+// записывая каждый шаг в журнал загрузки как цикл воркера.
+// Валюты, добавленные через админский API, курсов не получают.
+func (f *demoFeed) Run(ctx context.Context) {
+	ticker := time.NewTicker(demoStep)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.tick(ctx); err != nil {
+				log.Printf("demo feed: %v", err)
+			}
+		}
+	}
+}
+
+func (f *demoFeed) tick(ctx context.Context) error {
+	runID, err := f.repo.StartFetchRun(ctx, demoSource, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var rates []models.ExchangeRate
+	for _, currencyID := range sortedIDs(f.prices) {
+		price := f.step(currencyID)
+		for quote, rate := range demoQuotes {
+			rates = append(rates, models.ExchangeRate{
+				CurrencyID: currencyID,
+				Price:      demoDecimal(price * rate),
+				Quote:      quote,
+				Sources:    []string{demoSource},
+			})
+		}
+	}
+	if _, err := f.repo.SaveRates(ctx, rates); err != nil {
+		return err
+	}
+
+	return f.repo.FinishFetchRun(ctx, models.FetchRun{
+		ID:             runID,
+		CoinsRequested: len(f.prices),
+		RatesSaved:     len(rates),
+		Status:         models.FetchRunSuccess,
+	})
+}
+
+func sortedIDs(prices map[int]float64) []int {
+	ids := make([]int, 0, len(prices))
+	for id := range prices {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// demoDecimal округляет цену до восьми значащих цифр
+func demoDecimal(price float64) models.Decimal {
+	places := 8 - int(math.Ceil(math.Log10(price)))
+	if places < 0 {
+		places = 0
+	}
+	scale := math.Pow(10, float64(places))
+	d, err := models.DecimalFromFloat(math.Round(price*scale) / scale)
+	if err != nil {
+		return models.Decimal{}
+	}
+	return d
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +11,12 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// База часовых поясов для ?tz=, в образе alpine её нет
+	_ "time/tzdata"
 
+	"cryptorate-service/internal/api"
 	"cryptorate-service/internal/api/rest"
+	"cryptorate-service/internal/migrations"
 	"cryptorate-service/internal/repository"
 
 	"github.com/gorilla/mux"
@@ -19,35 +24,34 @@ import (
 )
 
 func main() {
-	// Подключение к БД
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		getEnv("POSTGRES_HOST", "localhost"),
-		getEnv("POSTGRES_PORT", "5432"),
-		getEnv("POSTGRES_USER", "crypto_user"),
-		getEnv("POSTGRES_PASSWORD", "secure_password_123"),
-		getEnv("POSTGRES_DB", "crypto_db"),
-	)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("DB connection failed:", err)
-	}
-	defer db.Close()
-
-	// Настраиваем пул соединений
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	if err := db.Ping(); err != nil {
-		log.Fatal("DB ping failed:", err)
+	demo := flag.Bool("demo", false, "serve synthetic rates from memory, without PostgreSQL")
+	flag.Parse()
+
+	var handler *rest.Handler
+	var adminHandler *rest.AdminHandler
+	if *demo {
+		// Демо-режим: данные в памяти процесса, курсы генерируются сами
+		repo := repository.NewMemoryRepository()
+		feed, err := newDemoFeed(context.Background(), repo, time.Now())
+		if err != nil {
+			log.Fatal("Demo data setup failed:", err)
+		}
+		feedCtx, stopFeed := context.WithCancel(context.Background())
+		defer stopFeed()
+		go feed.Run(feedCtx)
+		fmt.Println("🧪 Demo mode: serving synthetic rates from memory")
+
+		provider, err := api.NewProvider(api.ProviderStatic)
+		if err != nil {
+			log.Fatal("Provider setup failed:", err)
+		}
+		handler = rest.NewHandler(repo)
+		adminHandler = rest.NewAdminHandler(repo, provider)
+	} else {
+		var cleanup func()
+		handler, adminHandler, cleanup = setupPostgres()
+		defer cleanup()
 	}
-	fmt.Println("✅ Connected to database")
-
-	// Создаем репозиторий и хендлеры
-	repo := repository.NewRepository(db)
-	handler := rest.NewHandler(repo)
 
 	// Настраиваем роутер
 	router := mux.NewRouter()
@@ -63,6 +67,8 @@ func main() {
 	apiV1.HandleFunc("/rates", handler.GetRates).Methods("GET")
 	apiV1.HandleFunc("/rates/{currency}", handler.GetRate).Methods("GET")
 	apiV1.HandleFunc("/rates/{currency}/stats", handler.GetStats).Methods("GET")
+	apiV1.HandleFunc("/rates/{currency}/history", handler.GetHistory).Methods("GET")
+	apiV1.HandleFunc("/rates/{currency}/candles", handler.GetCandles).Methods("GET")
 
 	// Валюты
 	apiV1.HandleFunc("/currencies", handler.GetCurrencies).Methods("GET")
@@ -71,6 +77,14 @@ func main() {
 	// Системные
 	apiV1.HandleFunc("/health", handler.HealthCheck).Methods("GET")
 
+	// Администрирование (требует ADMIN_TOKEN)
+	admin := apiV1.PathPrefix("/admin").Subrouter()
+	admin.Use(rest.AdminAuth(getEnv("ADMIN_TOKEN", "")))
+	admin.HandleFunc("/currencies", adminHandler.CreateCurrency).Methods("POST")
+	admin.HandleFunc("/currencies/{id}", adminHandler.UpdateCurrency).Methods("PATCH")
+	admin.HandleFunc("/currencies/{id}", adminHandler.DeleteCurrency).Methods("DELETE")
+	admin.HandleFunc("/ingestion", adminHandler.GetIngestion).Methods("GET")
+
 	// Корневой маршрут
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -80,6 +94,8 @@ func main() {
             "endpoints": {
                 "rates": "/api/v1/rates",
                 "currency_stats": "/api/v1/rates/{currency}/stats",
+                "currency_history": "/api/v1/rates/{currency}/history",
+                "currency_candles": "/api/v1/rates/{currency}/candles?interval=1h",
                 "currencies": "/api/v1/currencies",
                 "health": "/api/v1/health"
             },
@@ -104,7 +120,7 @@ func main() {
 		fmt.Printf("📚 API docs: http://localhost:%s/api/v1/rates\n", port)
 		fmt.Printf("🏥 Health: http://localhost:%s/api/v1/health\n", port)
 
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {//ИГнорируем ошибку если сервер был остановлен с помощью graceful shutdown
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed { //ИГнорируем ошибку если сервер был остановлен с помощью graceful shutdown
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -124,6 +140,78 @@ func main() {
 	fmt.Println("👋 Server stopped")
 }
 
+// setupPostgres подключается к PostgreSQL и создаёт хендлеры поверх Repository.
+// cleanup закрывает слушатель уведомлений и соединения с базой.
+func setupPostgres() (handler *rest.Handler, adminHandler *rest.AdminHandler, cleanup func()) {
+	// Подключение к БД
+	connStr := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		getEnv("POSTGRES_HOST", "localhost"),
+		getEnv("POSTGRES_PORT", "5432"),
+		getEnv("POSTGRES_USER", "crypto_user"),
+		getEnv("POSTGRES_PASSWORD", "secure_password_123"),
+		getEnv("POSTGRES_DB", "crypto_db"),
+	)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatal("DB connection failed:", err)
+	}
+
+	// Настраиваем пул соединений
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := db.Ping(); err != nil {
+		log.Fatal("DB ping failed:", err)
+	}
+	fmt.Println("✅ Connected to database")
+
+	if getEnv("MIGRATE_ON_START", "") == "true" {
+		applied, err := migrations.Apply(context.Background(), db)
+		if err != nil {
+			log.Fatal("Migrations failed:", err)
+		}
+		fmt.Printf("✅ Schema is up to date (%d migrations applied)\n", applied)
+	}
+
+	// Создаем репозиторий и хендлеры. Курсы и статистика читаются через кэш,
+	// который сбрасывается по уведомлению базы о новых курсах.
+	queryTimeout, err := repository.ParseQueryTimeout(getEnv("DB_QUERY_TIMEOUT", ""))
+	if err != nil {
+		log.Fatal("Query timeout setup failed:", err)
+	}
+	repo := repository.NewRepository(db, repository.WithQueryTimeout(queryTimeout))
+	cached := repository.NewCachedRepository(repo, repository.DefaultCacheTTL)
+	listener, err := repository.ListenRatesChanged(connStr)
+	if err != nil {
+		log.Fatal("Rates listener failed:", err)
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go cached.Watch(watchCtx, listener.Notify)
+
+	handler = rest.NewHandler(cached)
+
+	// Источник курсов нужен админскому API для проверки новых валют
+	provider, err := api.NewProviders(getEnv("PRICE_PROVIDER", ""), api.DefaultMaxDeviation)
+	if err != nil {
+		log.Fatal("Provider setup failed:", err)
+	}
+	limits, err := api.ParseLimits(getEnv("RATE_LIMITS", ""))
+	if err != nil {
+		log.Fatal("Rate limits setup failed:", err)
+	}
+	api.SetLimits(provider, limits, repo)
+	adminHandler = rest.NewAdminHandler(repo, provider)
+
+	return handler, adminHandler, func() {
+		stopWatch()
+		listener.Close()
+		db.Close()
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -142,8 +230,8 @@ func loggingMiddleware(next http.Handler) http.Handler {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	// База часовых поясов для /timezone не зависит от образа
+	_ "time/tzdata"
 
 	"cryptorate-service/internal/bot"
+	"cryptorate-service/internal/migrations"
+	"cryptorate-service/internal/repository"
 	_ "github.com/lib/pq"
 )
 
@@ -27,16 +34,42 @@ func main() {
 		log.Fatal("DB ping failed", err)
 	}
 
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		applied, err := migrations.Apply(context.Background(), db)
+		if err != nil {
+			log.Fatal("Migrations failed:", err)
+		}
+		log.Printf("Schema is up to date (%d migrations applied)", applied)
+	}
+
 	token := os.Getenv("TELEGRAM_BOT_TOKEN")
 	if token == "" {
 		log.Fatal("TELEGRAM_BOT_TOKEN environment variable is required")
 	}
 
-	bot, err := bot.NewBot(token, db)
+	// Курсы читаются через кэш, который сбрасывается по уведомлению базы о новых курсах
+	queryTimeout, err := repository.ParseQueryTimeout(os.Getenv("DB_QUERY_TIMEOUT"))
+	if err != nil {
+		log.Fatal("Query timeout setup failed:", err)
+	}
+	repo := repository.NewCachedRepository(repository.NewRepository(db, repository.WithQueryTimeout(queryTimeout)), repository.DefaultCacheTTL)
+	listener, err := repository.ListenRatesChanged(connStr)
+	if err != nil {
+		log.Fatal("Rates listener failed:", err)
+	}
+	defer listener.Close()
+
+	bot, err := bot.NewBot(token, repo)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Ctrl+C или SIGTERM останавливают бота и прерывают запросы к БД
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go repo.Watch(ctx, listener.Notify)
+
 	log.Println("Bot started...")
-	bot.Start()
+	bot.Start(ctx)
+	log.Println("Bot stopped")
 }
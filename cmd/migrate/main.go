@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"cryptorate-service/internal/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+// Управление схемой БД: migrate up | migrate down [-steps N] | migrate status
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	db := openDB()
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal("❌ ", err)
+		}
+		fmt.Printf("✅ %d migrations applied\n", applied)
+
+	case "down":
+		fs := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "Number of migrations to roll back")
+		fs.Parse(os.Args[2:])
+		if *steps < 1 {
+			log.Fatal("Invalid -steps: expected a positive number")
+		}
+
+		rolledBack, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			log.Fatal("❌ ", err)
+		}
+		fmt.Printf("✅ %d migrations rolled back\n", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal("❌ ", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: migrate up | down [-steps N] | status")
+	os.Exit(2)
+}
+
+func openDB() *sql.DB {
+	connStr := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		getEnv("POSTGRES_HOST", "localhost"),
+		getEnv("POSTGRES_PORT", "5432"),
+		getEnv("POSTGRES_USER", "crypto_user"),
+		getEnv("POSTGRES_PASSWORD", "secure_password_123"),
+		getEnv("POSTGRES_DB", "crypto_db"),
+	)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatal("DB connection failed:", err)
+	}
+	if err := db.Ping(); err != nil {
+		log.Fatal("DB ping failed:", err)
+	}
+	return db
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"cryptorate-service/internal/api"
+	"cryptorate-service/internal/models"
+	"cryptorate-service/internal/repository"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Форматы дат, которые принимают --from и --to
+var backfillDateLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// runBackfill загружает историю курсов за период, чтобы статистика
+// (минимум и максимум за сутки, изменение за час) работала сразу после развёртывания
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	fromSpec := fs.String("from", "", "Start of the period: 2006-01-02, 2006-01-02T15:04 or RFC3339 (UTC)")
+	toSpec := fs.String("to", "", "End of the period, same formats as --from (default now)")
+	coinsSpec := fs.String("coins", "", "Coin ids, comma separated (default all currencies from the Currency table)")
+	providerName := fs.String("provider", getEnv("PRICE_PROVIDER", api.ProviderCoinGecko), "Price providers, comma separated; the first one with history support is used")
+	quotesSpec := fs.String("quotes", getEnv("QUOTE_CURRENCIES", "usd"), "Quote currencies, comma separated: usd, eur, rub, btc")
+	fs.Parse(args)
+
+	from, err := parseBackfillDate(*fromSpec)
+	if err != nil {
+		log.Fatal("Invalid --from: ", err)
+	}
+	to := time.Now().UTC()
+	if *toSpec != "" {
+		if to, err = parseBackfillDate(*toSpec); err != nil {
+			log.Fatal("Invalid --to: ", err)
+		}
+	}
+	if !from.Before(to) {
+		log.Fatalf("Invalid period: --from %s is not before --to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	client, err := api.NewProviders(*providerName, api.DefaultMaxDeviation)
+	if err != nil {
+		log.Fatal("Provider setup failed:", err)
+	}
+	history, ok := client.(api.HistoryProvider)
+	if !ok {
+		log.Fatalf("Price provider %s does not support history", client.Name())
+	}
+
+	db := openDB()
+	defer db.Close()
+	repo := newRepository(db)
+
+	limits, err := api.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatal("Rate limits setup failed:", err)
+	}
+	api.SetLimits(client, limits, repo)
+
+	// Ctrl+C прерывает загрузку, уже сохранённые курсы остаются в БД
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	currencies, err := backfillCurrencies(ctx, repo, *coinsSpec)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Курсы за период без месячной секции попали бы в Exchange_rate_default
+	if _, err := repo.EnsurePartitions(ctx, from, to); err != nil {
+		log.Fatal("Failed to create partitions: ", err)
+	}
+
+	quotes := api.ParseQuotes(*quotesSpec)
+	fmt.Printf("🚀 Backfill %s - %s for %d currencies (%s) from %s\n",
+		from.Format(time.RFC3339), to.Format(time.RFC3339), len(currencies), strings.Join(quotes, ","), client.Name())
+
+	var total int64
+	failed := 0
+	for _, currency := range currencies {
+		for _, quote := range quotes {
+			points, err := history.GetHistory(ctx, currency.NameCurrency, quote, from, to)
+			if ctx.Err() != nil {
+				fmt.Println("\n👋 Backfill cancelled")
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Printf("❌ %s/%s: %v\n", currency.NameCurrency, quote, err)
+				failed++
+				continue
+			}
+
+			inserted, err := repo.SaveHistoricalRates(ctx, currency.ID, quote, client.Name(), points)
+			if err != nil {
+				fmt.Printf("❌ Failed to save %s/%s: %v\n", currency.NameCurrency, quote, err)
+				failed++
+				continue
+			}
+
+			total += inserted
+			fmt.Printf("✅ %s/%s: %d points received, %d saved\n", currency.NameCurrency, quote, len(points), inserted)
+		}
+	}
+
+	// Загруженные курсы попадают в часы, которые уже могли быть агрегированы.
+	// Повторный запуск backfill пересчитает агрегаты заново, кроме часов,
+	// сырые курсы которых уже удалены по сроку хранения.
+	if total > 0 {
+		if _, err := repo.RefreshRollups(ctx, from); err != nil {
+			log.Fatalf("❌ Failed to refresh rollups for the backfilled period: %v", err)
+		}
+	}
+
+	fmt.Printf("✅ Backfill finished: %d rates saved\n", total)
+	if failed > 0 {
+		fmt.Printf("⚠️ %d currency/quote pairs failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+// backfillCurrencies возвращает валюты из --coins или все валюты таблицы Currency
+func backfillCurrencies(ctx context.Context, repo *repository.Repository, coinsSpec string) ([]models.Currency, error) {
+	all, err := repo.GetAllCurrencies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load currencies: %w", err)
+	}
+	if strings.TrimSpace(coinsSpec) == "" {
+		return all, nil
+	}
+
+	byName := make(map[string]models.Currency, len(all))
+	for _, currency := range all {
+		byName[currency.NameCurrency] = currency
+	}
+
+	var currencies []models.Currency
+	for _, name := range strings.Split(coinsSpec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		currency, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("currency %s not found in the Currency table", name)
+		}
+		currencies = append(currencies, currency)
+	}
+	return currencies, nil
+}
+
+func parseBackfillDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("date is required")
+	}
+	for _, layout := range backfillDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported date format %q", value)
+}
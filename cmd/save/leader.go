@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"cryptorate-service/internal/repository"
+	"fmt"
+	"log"
+)
+
+// workerLockKey ключ advisory-блокировки, которую держит ведущий воркер
+const workerLockKey int64 = 0x637279707461 // "crypta"
+
+// leader выбор ведущего среди нескольких реплик воркера. Курсы загружает
+// только владелец блокировки, остальные ждут и пробуют взять её на каждом тике,
+// поэтому после падения ведущего загрузка продолжается не позже чем через интервал.
+type leader struct {
+	repo *repository.Repository
+	lock *repository.AdvisoryLock
+}
+
+// ensure возвращает true, если процесс ведущий: проверяет удерживаемую
+// блокировку или пытается её взять
+func (l *leader) ensure(ctx context.Context) bool {
+	if l.lock != nil {
+		if l.lock.Held(ctx) {
+			return true
+		}
+		// Соединение с блокировкой разорвано, её уже может держать другая реплика
+		log.Println("⚠️ Leader lock lost")
+		l.lock.Release(ctx)
+		l.lock = nil
+	}
+
+	lock, err := l.repo.TryAdvisoryLock(ctx, workerLockKey)
+	if err != nil {
+		log.Printf("❌ Leader election failed: %v", err)
+		return false
+	}
+	if lock == nil {
+		return false
+	}
+
+	l.lock = lock
+	fmt.Println("👑 This worker is the leader now")
+	return true
+}
+
+// release отдаёт блокировку, чтобы резервная реплика сразу подхватила загрузку
+func (l *leader) release() {
+	if l.lock == nil {
+		return
+	}
+	if err := l.lock.Release(context.Background()); err != nil {
+		log.Printf("⚠️ Failed to release leader lock: %v", err)
+	}
+	l.lock = nil
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"context"
 	"cryptorate-service/internal/api"
+	"cryptorate-service/internal/migrations"
 	"cryptorate-service/internal/models"
 	"cryptorate-service/internal/repository"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,58 +23,90 @@ import (
 
 // Запус автоматической выгрузки по API курса валют с промежутком времени interval секунды
 func main() {
+	// Загрузка истории курсов: save backfill --from 2024-01-01 --coins bitcoin
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		runBackfill(os.Args[2:])
+		return
+	}
+
 	interval := flag.Int("interval", 0, "Update interval in MINUTES (0 = run once)")
+	providerName := flag.String("provider", os.Getenv("PRICE_PROVIDER"), "Price providers, comma separated: coingecko, binance, kraken, static (default coingecko)")
+	quotesSpec := flag.String("quotes", getEnv("QUOTE_CURRENCIES", "usd"), "Quote currencies, comma separated: usd, eur, rub, btc")
+	maxDeviation := flag.Float64("max-deviation", 2, "Max deviation from the median in PERCENT when several providers are used")
+	rollupInterval := flag.Int("rollup-interval", 10, "Partition and rollup maintenance interval in MINUTES")
+	retentionDays := flag.Int("retention-days", getEnvInt("RAW_RETENTION_DAYS", 0), "Keep raw rates for N DAYS, older ones are served from rollups (0 = keep forever)")
 	flag.Parse()
 
-	// Подключение к БД
-	// Добавлен fallback на значения по умолчанию
-	connStr := "host=127.0.0.1 port=5432 user=crypto_user password=secure_password_123 dbname=crypto_db sslmode=disable"
-
-	// Пробуем получить из .env, если не получилось - используем значения выше
-	if user := os.Getenv("POSTGRES_USER"); user != "" {
-		connStr = fmt.Sprintf("host=postgres port=5432 user=%s password=%s dbname=%s sslmode=disable",
-			os.Getenv("POSTGRES_USER"),
-			os.Getenv("POSTGRES_PASSWORD"),
-			os.Getenv("POSTGRES_DB"))
+	if *rollupInterval < 1 || *retentionDays < 0 {
+		log.Fatal("Invalid flags: --rollup-interval must be positive, --retention-days must not be negative")
 	}
 
-	db, err := sql.Open("postgres", connStr)
+	db := openDB()
+	defer db.Close()
+
+	repo := newRepository(db)
+	client, err := api.NewProviders(*providerName, *maxDeviation/100)
 	if err != nil {
-		log.Fatal("DB connection failed:", err)
+		log.Fatal("Provider setup failed:", err)
 	}
-	defer db.Close()
+	fmt.Printf("📡 Price provider: %s\n", client.Name())
 
-	if err := db.Ping(); err != nil {
-		log.Fatal("DB ping failed:", err)
+	// Лимит запросов общий с ботом и API через таблицу Api_request_budget
+	limits, err := api.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatal("Rate limits setup failed:", err)
 	}
-	fmt.Println("✅ Connected to database")
+	api.SetLimits(client, limits, repo)
+
+	quotes := api.ParseQuotes(*quotesSpec)
+	fmt.Printf("💱 Quote currencies: %s\n", strings.Join(quotes, ","))
+
+	//Добавлен graceful shutdown для мягкой остановки.
+	// Отмена контекста прерывает и запросы к источнику, включая повторы.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// При нескольких репликах курсы загружает только ведущая
+	election := &leader{repo: repo}
+	defer election.release()
 
-	repo := repository.NewRepository(db)
-	client := api.NewCoinGeckoClient()
+	maintenance := &maintenanceJob{repo: repo, retention: time.Duration(*retentionDays) * 24 * time.Hour}
 
 	if *interval == 0 {
 		// Одноразовый запуск
 		fmt.Println("🚀 One-time rates update")
-		updateRates(client, repo)
+		if !election.ensure(ctx) {
+			fmt.Println("⚠️ Another worker is the leader, skipping update")
+			return
+		}
+		updateRates(ctx, client, repo, quotes)
+		maintenance.run(ctx)
 	} else {
 		fmt.Printf("🚀 Worker started. Fetching rates every %d minutes...\n", *interval)
 		fmt.Println("Press Ctrl+C to stop")
 
-		//Добавлен graceful shutdown для мягкой остановки
-		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-		defer stop()
-
 		//Создает канал, который будет отсылать текущее время с периодичностью interal
 		ticker := time.NewTicker(time.Duration(*interval) * time.Minute)
 		defer ticker.Stop()
 
+		// Секции и агрегаты обслуживаются по своему таймеру, между загрузками курсов
+		maintenanceTicker := time.NewTicker(time.Duration(*rollupInterval) * time.Minute)
+		defer maintenanceTicker.Stop()
+
 		// Первый запуск сразу
-		updateRates(client, repo)
+		runIfLeader(ctx, election, client, repo, quotes)
+		if election.ensure(ctx) {
+			maintenance.run(ctx)
+		}
 
 		for {
 			select {
 			case <-ticker.C:
-				updateRates(client, repo)
+				runIfLeader(ctx, election, client, repo, quotes)
+			case <-maintenanceTicker.C:
+				if election.ensure(ctx) {
+					maintenance.run(ctx)
+				}
 			case <-ctx.Done():
 				fmt.Println("\n👋 Stopping worker...")
 				return
@@ -79,45 +115,221 @@ func main() {
 	}
 }
 
-func updateRates(client *api.CoinGeckoClient, repo *repository.Repository) {
+// runIfLeader обновляет курсы, если процесс ведущий, иначе ждёт следующего тика
+func runIfLeader(ctx context.Context, election *leader, client api.PriceProvider, repo *repository.Repository, quotes []string) {
+	if !election.ensure(ctx) {
+		fmt.Printf("\n💤 [%s] Standby: another worker is the leader\n", time.Now().Format("15:04"))
+		return
+	}
+	updateRates(ctx, client, repo, quotes)
+}
+
+func updateRates(ctx context.Context, client api.PriceProvider, repo *repository.Repository, quotes []string) {
 	//Добавлен timestamp в логи
 	currentTime := time.Now().Format("15:04")
-	fmt.Printf("\n⏰ [%s] Fetching rates for 7 currencies...\n", currentTime)
-
-	coinIDs := []string{
-        "bitcoin",
-        "ethereum", 
-        "tether",
-        "binancecoin",
-        "solana",
-        "ripple",
-        "cardano",
-    }
-
-	prices, err := client.GetPrices(coinIDs)
+
+	// Каждый цикл записывается в Fetch_runs, итоги доступны в /api/v1/admin/ingestion
+	run := models.FetchRun{StartedAt: time.Now(), Provider: client.Name(), Status: models.FetchRunRunning}
+	runID, err := repo.StartFetchRun(ctx, run.Provider, run.StartedAt)
+	if err != nil {
+		log.Printf("⚠️ Failed to record fetch run: %v", err)
+	}
+	run.ID = runID
+	defer finishFetchRun(ctx, repo, &run)
+
+	// Список валют читаем из БД в каждом цикле: добавление или отключение
+	// валюты в таблице Currency не требует перезапуска воркера
+	currencies, err := repo.GetTrackedCurrencies(ctx)
 	if err != nil {
+		log.Printf("❌ Failed to load currencies: %v", err)
+		run.Status, run.Error = models.FetchRunFailed, fmt.Sprintf("failed to load currencies: %v", err)
+		return
+	}
+	if len(currencies) == 0 {
+		fmt.Printf("\n⏰ [%s] No tracked currencies, skipping\n", currentTime)
+		run.Status = models.FetchRunSkipped
+		return
+	}
+
+	// Биржевым источникам нужны тикеры валют из таблицы Currency
+	api.SetSymbols(client, currencies)
+
+	coinIDs := make([]string, len(currencies))
+	currencyIDs := make(map[string]int, len(currencies))
+	for i, currency := range currencies {
+		coinIDs[i] = currency.NameCurrency
+		currencyIDs[currency.NameCurrency] = currency.ID
+	}
+	run.CoinsRequested = len(coinIDs)
+
+	fmt.Printf("\n⏰ [%s] Fetching rates for %d currencies...\n", currentTime, len(coinIDs))
+
+	prices, err := client.GetPrices(ctx, coinIDs, quotes...)
+	switch {
+	case ctx.Err() != nil:
+		fmt.Printf("⚠️ [%s] Update cancelled\n", currentTime)
+		run.Status, run.Error = models.FetchRunFailed, "cancelled"
+		return
+	case errors.Is(err, api.ErrRateLimited):
+		log.Printf("❌ API rate limit exceeded, retry budget exhausted: %v", err)
+		run.Status, run.Error = models.FetchRunFailed, err.Error()
+		return
+	case err != nil:
 		log.Printf("❌ API error: %v", err)
+		run.Status, run.Error = models.FetchRunFailed, err.Error()
 		return
 	}
 
+	// Валюты, по которым не сохранён хотя бы один курс
+	skipped := make(map[string]bool)
+	for _, id := range coinIDs {
+		if _, ok := prices[id]; !ok {
+			fmt.Printf("⚠️ No rates for %s, skipping\n", id)
+			skipped[id] = true
+		}
+	}
+
+	// Курсы цикла сохраняются одной транзакцией с общим временем
+	var rates []models.ExchangeRate
+	var logLines []string
 	for coinName, data := range prices {
-		currencyID, err := repo.GetCurrencyID(coinName)
-		if err != nil {
+		currencyID, ok := currencyIDs[coinName]
+		if !ok {
 			fmt.Printf("⚠️ Currency %s not found, skipping\n", coinName)
 			continue
 		}
 
-		err = repo.SaveRate(models.ExchangeRate{
-			CurrencyID: currencyID,
-			Price:      data.USD,
-		})
+		for _, quote := range quotes {
+			price, ok := data.Price(quote)
+			if !ok {
+				fmt.Printf("⚠️ No %s price for %s, skipping\n", quote, coinName)
+				skipped[coinName] = true
+				continue
+			}
+
+			// У одиночного источника список источников не заполняется
+			sources, rejected := data.QuoteSources(quote)
+			if len(sources) == 0 {
+				sources = []string{client.Name()}
+			}
 
+			rate := models.ExchangeRate{
+				CurrencyID: currencyID,
+				Price:      price,
+				Quote:      quote,
+				Sources:    sources,
+				Rejected:   rejected,
+			}
+			if market, ok := data.MarketData(quote); ok {
+				rate.Market = &market
+			}
+			rates = append(rates, rate)
+
+			if rejected > 0 {
+				logLines = append(logLines, fmt.Sprintf("✅ %s: %s %s (sources: %s, rejected: %d)",
+					coinName, price, quote, strings.Join(sources, ","), rejected))
+			} else {
+				logLines = append(logLines, fmt.Sprintf("✅ %s: %s %s", coinName, price, quote))
+			}
+		}
+	}
+
+	if _, err := repo.SaveRates(ctx, rates); err != nil {
+		fmt.Printf("❌ Failed to save rates: %v\n", err)
+		run.Status, run.Error = models.FetchRunFailed, fmt.Sprintf("failed to save rates: %v", err)
+		run.CoinsSkipped = len(coinIDs)
+		return
+	}
+	run.RatesSaved = len(rates)
+	for _, line := range logLines {
+		fmt.Println(line)
+	}
+
+	run.CoinsSkipped = len(skipped)
+	switch {
+	case run.RatesSaved == 0:
+		run.Status = models.FetchRunFailed
+		if run.Error == "" {
+			run.Error = "no rates received"
+		}
+	case run.CoinsSkipped > 0:
+		run.Status = models.FetchRunPartial
+	default:
+		run.Status = models.FetchRunSuccess
+	}
+
+	fmt.Printf("✅ [%s] Rates updated: %d saved, %d currencies skipped\n", currentTime, run.RatesSaved, run.CoinsSkipped)
+}
+
+// finishFetchRun сохраняет итоги цикла, если его начало удалось записать.
+// Итоги записываются и после отмены ctx, чтобы прерванный цикл не остался running.
+func finishFetchRun(ctx context.Context, repo *repository.Repository, run *models.FetchRun) {
+	if run.ID == 0 {
+		return
+	}
+	if err := repo.FinishFetchRun(context.WithoutCancel(ctx), *run); err != nil {
+		log.Printf("⚠️ Failed to record fetch run result: %v", err)
+	}
+}
+
+// openDB подключается к БД и проверяет соединение
+func openDB() *sql.DB {
+	// Подключение к БД
+	// Добавлен fallback на значения по умолчанию
+	connStr := "host=127.0.0.1 port=5432 user=crypto_user password=secure_password_123 dbname=crypto_db sslmode=disable"
+
+	// Пробуем получить из .env, если не получилось - используем значения выше
+	if user := os.Getenv("POSTGRES_USER"); user != "" {
+		connStr = fmt.Sprintf("host=postgres port=5432 user=%s password=%s dbname=%s sslmode=disable",
+			os.Getenv("POSTGRES_USER"),
+			os.Getenv("POSTGRES_PASSWORD"),
+			os.Getenv("POSTGRES_DB"))
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatal("DB connection failed:", err)
+	}
+	if err := db.Ping(); err != nil {
+		log.Fatal("DB ping failed:", err)
+	}
+	fmt.Println("✅ Connected to database")
+
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		applied, err := migrations.Apply(context.Background(), db)
 		if err != nil {
-			fmt.Printf("❌ Failed to save %s: %v\n", coinName, err)
-		} else {
-			fmt.Printf("✅ %s: $%.2f\n", coinName, data.USD)
+			log.Fatal("Migrations failed:", err)
 		}
+		fmt.Printf("✅ Schema is up to date (%d migrations applied)\n", applied)
 	}
 
-	fmt.Printf("✅ [%s] Rates updated\n", currentTime)
+	return db
+}
+
+// newRepository создаёт репозиторий с ограничением времени запросов из DB_QUERY_TIMEOUT
+func newRepository(db *sql.DB) *repository.Repository {
+	timeout, err := repository.ParseQueryTimeout(os.Getenv("DB_QUERY_TIMEOUT"))
+	if err != nil {
+		log.Fatal("Query timeout setup failed:", err)
+	}
+	return repository.NewRepository(db, repository.WithQueryTimeout(timeout))
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return parsed
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
 }
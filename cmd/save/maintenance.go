@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"cryptorate-service/internal/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+// partitionsAhead на сколько месяцев вперёд создаются секции Exchange_rate
+const partitionsAhead = 2
+
+// maintenanceJob обслуживает таблицы курсов: создаёт месячные секции
+// Exchange_rate заранее, поддерживает часовые и дневные агрегаты и удаляет
+// сырые курсы старше срока хранения. Запускается только ведущим воркером.
+type maintenanceJob struct {
+	repo *repository.Repository
+	// retention срок хранения сырых курсов, ноль - хранить бессрочно
+	retention time.Duration
+}
+
+func (j *maintenanceJob) run(ctx context.Context) {
+	// Без секции курсы попадают в Exchange_rate_default, поэтому ошибка
+	// не мешает остальному обслуживанию
+	now := time.Now().UTC()
+	created, err := j.repo.EnsurePartitions(ctx, now, now.AddDate(0, partitionsAhead, 0))
+	if err != nil {
+		log.Printf("❌ Failed to create partitions: %v", err)
+	} else if created > 0 {
+		fmt.Printf("🗂️ Created %d Exchange_rate partitions\n", created)
+	}
+
+	updated, err := j.repo.RefreshRollups(ctx, time.Time{})
+	if err != nil {
+		log.Printf("❌ Failed to refresh rollups: %v", err)
+		return
+	}
+	fmt.Printf("📊 Rollups refreshed: %d hours updated\n", updated)
+
+	if j.retention <= 0 {
+		return
+	}
+	// Удаляются только агрегированные часы, поэтому сначала обновляем агрегаты
+	deleted, err := j.repo.PruneRawRates(ctx, time.Now().Add(-j.retention))
+	if err != nil {
+		log.Printf("❌ Failed to prune raw rates: %v", err)
+		return
+	}
+	if deleted > 0 {
+		fmt.Printf("🧹 Pruned %d raw rates older than %s\n", deleted, j.retention)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxDeviation допустимое отклонение курса от медианы (2%)
+const DefaultMaxDeviation = 0.02
+
+// Aggregator опрашивает несколько источников одновременно и возвращает
+// согласованный курс: медиану котировок, не отклонившихся от общей медианы
+// больше чем на maxDeviation. Принятые источники и число отброшенных
+// котировок сохраняются отдельно для каждой валюты котировки (см. QuoteSources).
+type Aggregator struct {
+	providers    []PriceProvider
+	maxDeviation float64
+}
+
+func NewAggregator(providers []PriceProvider, maxDeviation float64) *Aggregator {
+	if maxDeviation <= 0 {
+		maxDeviation = DefaultMaxDeviation
+	}
+	return &Aggregator{providers: providers, maxDeviation: maxDeviation}
+}
+
+// Name возвращает имена всех источников через запятую
+func (a *Aggregator) Name() string {
+	names := make([]string, len(a.providers))
+	for i, provider := range a.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// SetSymbols передаёт тикеры всем источникам, которым они нужны
+func (a *Aggregator) SetSymbols(symbols map[string]string) {
+	for _, provider := range a.providers {
+		if setter, ok := provider.(SymbolSetter); ok {
+			setter.SetSymbols(symbols)
+		}
+	}
+}
+
+// SetLimits передаёт ограничения частоты запросов всем источникам
+func (a *Aggregator) SetLimits(limits map[string]LimitConfig, budget RequestBudget) {
+	for _, provider := range a.providers {
+		SetLimits(provider, limits, budget)
+	}
+}
+
+// sourceQuote котировка валюты от конкретного источника
+type sourceQuote struct {
+	source string
+	price  models.Decimal
+	// market рыночные показатели источника, nil если он их не отдал
+	market *models.MarketData
+}
+
+// GetPrices опрашивает источники параллельно. Ошибка отдельного источника
+// не прерывает цикл, ошибка возвращается только если не ответил ни один.
+// Согласованный курс считается отдельно для каждой валюты котировки.
+func (a *Aggregator) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
+	quotes = normalizeQuotes(quotes)
+	responses := make([]models.CoinGeckoResponse, len(a.providers))
+	errs := make([]error, len(a.providers))
+
+	var wg sync.WaitGroup
+	for i, provider := range a.providers {
+		wg.Add(1)
+		go func(i int, provider PriceProvider) {
+			defer wg.Done()
+			responses[i], errs[i] = provider.GetPrices(ctx, coinIDs, quotes...)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	// Валюта -> котировка -> ответы источников
+	quotesByCoin := make(map[string]map[string][]sourceQuote, len(coinIDs))
+	failed := 0
+	for i, provider := range a.providers {
+		if errs[i] != nil {
+			log.Printf("aggregator: %s failed: %v", provider.Name(), errs[i])
+			failed++
+			continue
+		}
+		for id, data := range responses[i] {
+			for _, quote := range quotes {
+				price, ok := data.Price(quote)
+				if !ok || price.Sign() <= 0 {
+					continue
+				}
+				if quotesByCoin[id] == nil {
+					quotesByCoin[id] = make(map[string][]sourceQuote)
+				}
+				coinQuote := sourceQuote{source: provider.Name(), price: price}
+				if market, ok := data.MarketData(quote); ok {
+					coinQuote.market = &market
+				}
+				quotesByCoin[id][quote] = append(quotesByCoin[id][quote], coinQuote)
+			}
+		}
+	}
+
+	if failed == len(a.providers) {
+		return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+	}
+
+	result := make(models.CoinGeckoResponse, len(quotesByCoin))
+	for id, byQuote := range quotesByCoin {
+		var entry models.PriceQuote
+		for quote, coinQuotes := range byQuote {
+			price, accepted, ok := a.consensus(coinQuotes)
+			if !ok {
+				log.Printf("aggregator: %s/%s has no consensus, %d quotes rejected", id, quote, len(coinQuotes))
+				continue
+			}
+			entry.SetPrice(quote, price)
+			// Рыночные показатели не усредняются: берутся у первого принятого
+			// источника, который их отдал. Источник-выброс их не задаёт.
+			for _, q := range accepted {
+				if q.market != nil {
+					entry.SetMarket(quote, *q.market)
+					break
+				}
+			}
+			sources := make([]string, len(accepted))
+			for i, q := range accepted {
+				sources[i] = q.source
+			}
+			sort.Strings(sources)
+			entry.SetSources(quote, sources, len(coinQuotes)-len(accepted))
+		}
+
+		if len(entry.Prices) == 0 {
+			continue
+		}
+		result[id] = entry
+	}
+
+	return result, nil
+}
+
+// consensus отбрасывает выбросы и возвращает медиану оставшихся котировок
+// вместе с принятыми котировками в порядке источников
+func (a *Aggregator) consensus(quotes []sourceQuote) (models.Decimal, []sourceQuote, bool) {
+	prices := make([]models.Decimal, len(quotes))
+	for i, q := range quotes {
+		prices[i] = q.price
+	}
+	center := median(prices)
+
+	var acceptedPrices []models.Decimal
+	var accepted []sourceQuote
+	for _, q := range quotes {
+		// Отклонение - относительная величина, точности float64 для неё достаточно
+		if math.Abs(q.price.Sub(center).Float64())/center.Float64() > a.maxDeviation {
+			continue
+		}
+		acceptedPrices = append(acceptedPrices, q.price)
+		accepted = append(accepted, q)
+	}
+
+	if len(accepted) == 0 {
+		return models.Decimal{}, nil, false
+	}
+
+	return median(acceptedPrices), accepted, true
+}
+
+// half множитель для среднего двух центральных значений медианы
+var half = models.MustParseDecimal("0.5")
+
+// median возвращает медиану, исходный срез не изменяется
+func median(values []models.Decimal) models.Decimal {
+	sorted := append([]models.Decimal(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return sorted[n/2-1].Add(sorted[n/2]).Mul(half)
+}
+
+// GetHistory берёт историю у первого источника, который её поддерживает и ответил.
+// Исторические курсы не агрегируются: у бирж нет общего формата истории.
+func (a *Aggregator) GetHistory(ctx context.Context, coinID, quote string, from, to time.Time) ([]models.PricePoint, error) {
+	var errs []error
+	for _, provider := range a.providers {
+		history, ok := provider.(HistoryProvider)
+		if !ok {
+			continue
+		}
+		points, err := history.GetHistory(ctx, coinID, quote, from, to)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		return points, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no provider in %s supports history", a.Name())
+	}
+	return nil, errors.Join(errs...)
+}
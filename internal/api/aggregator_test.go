@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// failingProvider источник, который всегда возвращает ошибку
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
+	return nil, errors.New("upstream unavailable")
+}
+
+// namedProvider статический источник с заданным именем
+type namedProvider struct {
+	*StaticProvider
+	name string
+}
+
+func (p namedProvider) Name() string { return p.name }
+
+func newNamedProvider(name string, prices map[string]float64) namedProvider {
+	return namedProvider{StaticProvider: NewStaticProvider(prices), name: name}
+}
+
+func TestAggregator_GetPrices(t *testing.T) {
+	aggregator := NewAggregator([]PriceProvider{
+		newNamedProvider("coingecko", map[string]float64{"bitcoin": 45000, "ethereum": 2500}),
+		newNamedProvider("binance", map[string]float64{"bitcoin": 45100, "ethereum": 2510}),
+		newNamedProvider("kraken", map[string]float64{"bitcoin": 60000}),
+	}, 0.02)
+
+	prices, err := aggregator.GetPrices(context.Background(), []string{"bitcoin", "ethereum"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	btc := prices["bitcoin"]
+	if btc.USD.String() != "45050" {
+		t.Errorf("Expected consensus 45050, got %s", btc.USD)
+	}
+	sources, rejected := btc.QuoteSources("usd")
+	if rejected != 1 {
+		t.Errorf("Expected 1 rejected quote, got %d", rejected)
+	}
+	if !reflect.DeepEqual(sources, []string{"binance", "coingecko"}) {
+		t.Errorf("Unexpected sources: %v", sources)
+	}
+
+	eth := prices["ethereum"]
+	sources, rejected = eth.QuoteSources("usd")
+	if eth.USD.String() != "2505" || rejected != 0 || len(sources) != 2 {
+		t.Errorf("Ethereum consensus incorrect. Got %+v", eth)
+	}
+}
+
+func TestAggregator_PartialFailure(t *testing.T) {
+	aggregator := NewAggregator([]PriceProvider{
+		failingProvider{},
+		newNamedProvider("coingecko", map[string]float64{"bitcoin": 45000}),
+	}, 0.02)
+
+	prices, err := aggregator.GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if btc := prices["bitcoin"]; btc.USD.String() != "45000" || len(btc.Sources["usd"]) != 1 {
+		t.Errorf("Expected price from the remaining provider. Got %+v", btc)
+	}
+}
+
+func TestAggregator_AllFailed(t *testing.T) {
+	aggregator := NewAggregator([]PriceProvider{failingProvider{}, failingProvider{}}, 0.02)
+
+	_, err := aggregator.GetPrices(context.Background(), []string{"bitcoin"})
+	if err == nil {
+		t.Error("Expected error when all providers failed")
+	}
+}
+
+func TestAggregator_NoConsensus(t *testing.T) {
+	// Два источника расходятся сильнее порога: выбрать верный невозможно
+	aggregator := NewAggregator([]PriceProvider{
+		newNamedProvider("coingecko", map[string]float64{"bitcoin": 45000}),
+		newNamedProvider("binance", map[string]float64{"bitcoin": 50000}),
+	}, 0.02)
+
+	prices, err := aggregator.GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if _, ok := prices["bitcoin"]; ok {
+		t.Error("Bitcoin should be skipped without consensus")
+	}
+}
+
+func TestNewProviders(t *testing.T) {
+	provider, err := NewProviders("coingecko, binance,kraken", 0.05)
+	if err != nil {
+		t.Fatalf("NewProviders failed: %v", err)
+	}
+
+	aggregator, ok := provider.(*Aggregator)
+	if !ok {
+		t.Fatalf("Expected *Aggregator, got %T", provider)
+	}
+	if aggregator.Name() != "coingecko,binance,kraken" {
+		t.Errorf("Unexpected name: %s", aggregator.Name())
+	}
+
+	if _, err := NewProviders("coingecko,unknown", 0.05); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+	if _, err := NewProviders("coingecko, Coingecko", 0.05); err == nil {
+		t.Error("Expected error for duplicate provider")
+	}
+
+	// Пустые элементы не создают лишний CoinGecko
+	provider, err = NewProviders(" binance, ", 0.05)
+	if err != nil {
+		t.Fatalf("NewProviders failed: %v", err)
+	}
+	if provider.Name() != ProviderBinance {
+		t.Errorf("Expected single binance provider, got %s", provider.Name())
+	}
+}
+
+func TestMedian(t *testing.T) {
+	testCases := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"3"}, "3"},
+		{[]string{"3", "1", "2"}, "2"},
+		{[]string{"4", "1", "3", "2"}, "2.5"},
+		{[]string{"0.00001235", "0.00001234"}, "0.000012345"},
+	}
+
+	for _, tc := range testCases {
+		values := make([]models.Decimal, len(tc.values))
+		for i, v := range tc.values {
+			values[i] = models.MustParseDecimal(v)
+		}
+		if got := median(values); got.String() != tc.want {
+			t.Errorf("median(%v) = %v, want %v", tc.values, got, tc.want)
+		}
+	}
+}
+
+// historyProvider источник с фиксированной историей курсов
+type historyProvider struct {
+	namedProvider
+	points []models.PricePoint
+	err    error
+}
+
+func (p historyProvider) GetHistory(ctx context.Context, coinID, quote string, from, to time.Time) ([]models.PricePoint, error) {
+	return p.points, p.err
+}
+
+func TestAggregator_GetHistory(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []models.PricePoint{{Time: from, Price: models.DecimalFromInt(42000)}}
+
+	aggregator := NewAggregator([]PriceProvider{
+		newNamedProvider("binance", nil),
+		historyProvider{namedProvider: newNamedProvider("broken", nil), err: errors.New("unavailable")},
+		historyProvider{namedProvider: newNamedProvider("coingecko", nil), points: points},
+	}, DefaultMaxDeviation)
+
+	got, err := aggregator.GetHistory(context.Background(), "bitcoin", "usd", from, from.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, points) {
+		t.Errorf("Expected %v, got %v", points, got)
+	}
+
+	// Ни один источник не поддерживает историю
+	aggregator = NewAggregator([]PriceProvider{newNamedProvider("binance", nil)}, DefaultMaxDeviation)
+	if _, err := aggregator.GetHistory(context.Background(), "bitcoin", "usd", from, from.Add(time.Hour)); err == nil {
+		t.Error("Expected error when no provider supports history")
+	}
+}
+
+// fixedProvider источник с заранее заданным ответом
+type fixedProvider struct {
+	name     string
+	response models.CoinGeckoResponse
+}
+
+func (p fixedProvider) Name() string { return p.name }
+
+func (p fixedProvider) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
+	return p.response, nil
+}
+
+func TestAggregator_GetPrices_MarketData(t *testing.T) {
+	var withMarket models.PriceQuote
+	withMarket.SetPrice("usd", models.DecimalFromInt(45000))
+	withMarket.SetMarket("usd", models.MarketData{MarketCap: 880000000000, Volume24h: 25000000000, Change24h: 1.5})
+
+	aggregator := NewAggregator([]PriceProvider{
+		newNamedProvider("binance", map[string]float64{"bitcoin": 45100}),
+		fixedProvider{name: "coingecko", response: models.CoinGeckoResponse{"bitcoin": withMarket}},
+	}, DefaultMaxDeviation)
+
+	prices, err := aggregator.GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	market, ok := prices["bitcoin"].MarketData("usd")
+	if !ok || market.MarketCap != 880000000000 || market.Change24h != 1.5 {
+		t.Errorf("Expected market data from coingecko, got %+v, %v", market, ok)
+	}
+}
+
+func TestAggregator_GetPrices_MarketDataFromOutlier(t *testing.T) {
+	// Единственный источник с рыночными данными отброшен как выброс
+	var outlier models.PriceQuote
+	outlier.SetPrice("usd", models.DecimalFromInt(60000))
+	outlier.SetMarket("usd", models.MarketData{MarketCap: 1, Volume24h: 1, Change24h: 33})
+
+	aggregator := NewAggregator([]PriceProvider{
+		fixedProvider{name: "coingecko", response: models.CoinGeckoResponse{"bitcoin": outlier}},
+		newNamedProvider("binance", map[string]float64{"bitcoin": 45000}),
+		newNamedProvider("kraken", map[string]float64{"bitcoin": 45100}),
+	}, DefaultMaxDeviation)
+
+	prices, err := aggregator.GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	btc := prices["bitcoin"]
+	if btc.USD.String() != "45050" {
+		t.Errorf("Expected consensus 45050, got %s", btc.USD)
+	}
+	if market, ok := btc.MarketData("usd"); ok {
+		t.Errorf("Expected no market data from rejected source, got %+v", market)
+	}
+}
+
+func TestAggregator_GetPrices_SourcesPerQuote(t *testing.T) {
+	// Kraken расходится с остальными только в USD
+	var kraken models.PriceQuote
+	kraken.SetPrice("usd", models.DecimalFromInt(60000))
+	kraken.SetPrice("eur", models.DecimalFromInt(41050))
+
+	var coingecko, binance models.PriceQuote
+	coingecko.SetPrice("usd", models.DecimalFromInt(45000))
+	coingecko.SetPrice("eur", models.DecimalFromInt(41000))
+	binance.SetPrice("usd", models.DecimalFromInt(45100))
+	binance.SetPrice("eur", models.DecimalFromInt(41100))
+
+	aggregator := NewAggregator([]PriceProvider{
+		fixedProvider{name: "coingecko", response: models.CoinGeckoResponse{"bitcoin": coingecko}},
+		fixedProvider{name: "binance", response: models.CoinGeckoResponse{"bitcoin": binance}},
+		fixedProvider{name: "kraken", response: models.CoinGeckoResponse{"bitcoin": kraken}},
+	}, DefaultMaxDeviation)
+
+	prices, err := aggregator.GetPrices(context.Background(), []string{"bitcoin"}, "usd", "eur")
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	btc := prices["bitcoin"]
+	if sources, rejected := btc.QuoteSources("usd"); rejected != 1 || !reflect.DeepEqual(sources, []string{"binance", "coingecko"}) {
+		t.Errorf("USD: expected binance,coingecko with 1 rejected, got %v, %d", sources, rejected)
+	}
+	// Выброс в USD не попадает в EUR
+	if sources, rejected := btc.QuoteSources("eur"); rejected != 0 || !reflect.DeepEqual(sources, []string{"binance", "coingecko", "kraken"}) {
+		t.Errorf("EUR: expected binance,coingecko,kraken with 0 rejected, got %v, %d", sources, rejected)
+	}
+}
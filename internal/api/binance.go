@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// binanceQuoteAssets валюты котировки, которые на Binance называются иначе:
+// долларовые пары торгуются к USDT
+var binanceQuoteAssets = map[string]string{
+	"usd": "USDT",
+}
+
+// binanceQuoteAsset возвращает тикер валюты котировки на Binance (usd -> USDT, eur -> EUR)
+func binanceQuoteAsset(quote string) string {
+	if asset, ok := binanceQuoteAssets[quote]; ok {
+		return asset
+	}
+	return strings.ToUpper(quote)
+}
+
+// BinanceClient получает спотовые курсы с биржи Binance
+type BinanceClient struct {
+	baseURL string
+	client  *http.Client
+	limiter *Limiter
+	symbolTable
+}
+
+func NewBinanceClient() *BinanceClient {
+	return &BinanceClient{
+		baseURL: "https://api.binance.com/api/v3",
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		limiter: NewLimiter(ProviderBinance, DefaultLimits[ProviderBinance], nil),
+	}
+}
+
+// Name возвращает имя источника
+func (c *BinanceClient) Name() string {
+	return ProviderBinance
+}
+
+// SetLimits заменяет ограничение частоты запросов
+func (c *BinanceClient) SetLimits(limits map[string]LimitConfig, budget RequestBudget) {
+	c.limiter = NewLimiter(c.Name(), limits[c.Name()], budget)
+}
+
+type binanceTicker struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+}
+
+// GetPrices запрашивает все тикеры одним запросом и выбирает нужные пары.
+// Валюты без тикера или без пары на бирже пропускаются.
+func (c *BinanceClient) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
+	quotes = normalizeQuotes(quotes)
+
+	// Пара -> идентификатор валюты и котировка (BTCUSDT -> bitcoin, usd)
+	type pairTarget struct {
+		coinID string
+		quote  string
+	}
+	pairs := make(map[string]pairTarget, len(coinIDs)*len(quotes))
+	result := make(models.CoinGeckoResponse, len(coinIDs))
+	for _, id := range coinIDs {
+		symbol, ok := c.symbol(ctx, id)
+		if !ok {
+			continue
+		}
+		for _, quote := range quotes {
+			asset := binanceQuoteAsset(quote)
+			if symbol == asset {
+				// Котировка к самой себе всегда равна 1
+				entry := result[id]
+				entry.SetPrice(quote, models.DecimalFromInt(1))
+				result[id] = entry
+				continue
+			}
+			pairs[symbol+asset] = pairTarget{coinID: id, quote: quote}
+		}
+	}
+
+	if len(pairs) == 0 {
+		return result, nil
+	}
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/ticker/price", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var tickers []binanceTicker
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, payloadError(err)
+	}
+
+	for _, ticker := range tickers {
+		target, ok := pairs[ticker.Symbol]
+		if !ok {
+			continue
+		}
+		price, err := models.ParseDecimal(ticker.Price)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid price %q for %s: %v", ErrBadPayload, ticker.Price, ticker.Symbol, err)
+		}
+		entry := result[target.coinID]
+		entry.SetPrice(target.quote, price)
+		result[target.coinID] = entry
+	}
+
+	return result, nil
+}
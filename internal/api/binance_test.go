@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBinanceClient_GetPrices(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ticker/price" {
+			t.Errorf("Expected path /ticker/price, got %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+            {"symbol": "BTCUSDT", "price": "45000.50000000"},
+            {"symbol": "ETHBTC", "price": "0.05500000"},
+            {"symbol": "ETHUSDT", "price": "2500.75000000"}
+        ]`))
+	}))
+	defer testServer.Close()
+
+	client := &BinanceClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	prices, err := client.GetPrices(context.Background(), []string{"bitcoin", "ethereum", "tether", "unknowncoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if len(prices) != 3 {
+		t.Errorf("Expected 3 prices, got %d", len(prices))
+	}
+
+	if btc, ok := prices["bitcoin"]; !ok || btc.USD.String() != "45000.5" {
+		t.Errorf("Bitcoin price incorrect. Got %+v", btc)
+	}
+
+	if eth, ok := prices["ethereum"]; !ok || eth.USD.String() != "2500.75" {
+		t.Errorf("Ethereum price incorrect. Got %+v", eth)
+	}
+
+	if usdt, ok := prices["tether"]; !ok || usdt.USD.String() != "1" {
+		t.Errorf("Tether price should be 1. Got %+v", usdt)
+	}
+}
+
+func TestBinanceClient_GetPrices_Quotes(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+            {"symbol": "BTCEUR", "price": "41000.25"},
+            {"symbol": "ETHBTC", "price": "0.055"}
+        ]`))
+	}))
+	defer testServer.Close()
+
+	client := &BinanceClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	prices, err := client.GetPrices(context.Background(), []string{"bitcoin", "ethereum"}, "eur", "btc")
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if eur, ok := prices["bitcoin"].Price("eur"); !ok || eur.String() != "41000.25" {
+		t.Errorf("Bitcoin EUR price incorrect. Got %v", eur)
+	}
+
+	if btc, ok := prices["bitcoin"].Price("btc"); !ok || btc.String() != "1" {
+		t.Errorf("Bitcoin BTC price should be 1. Got %v", btc)
+	}
+
+	if btc, ok := prices["ethereum"].Price("btc"); !ok || btc.String() != "0.055" {
+		t.Errorf("Ethereum BTC price incorrect. Got %v", btc)
+	}
+}
+
+func TestBinanceClient_SetSymbols(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"symbol": "DOGEUSDT", "price": "0.08"}]`))
+	}))
+	defer testServer.Close()
+
+	client := &BinanceClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	SetSymbols(client, []models.Currency{
+		{NameCurrency: "dogecoin", Symbol: "doge"},
+	})
+
+	prices, err := client.GetPrices(context.Background(), []string{"dogecoin", "bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if doge, ok := prices["dogecoin"]; !ok || doge.USD.String() != "0.08" {
+		t.Errorf("Dogecoin price incorrect. Got %+v", doge)
+	}
+
+	// После SetSymbols тикеры по умолчанию больше не используются
+	if _, ok := prices["bitcoin"]; ok {
+		t.Error("Bitcoin should be skipped without a symbol")
+	}
+}
+
+func TestBinanceClient_GetPrices_Error(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer testServer.Close()
+
+	client := &BinanceClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	_, err := client.GetPrices(context.Background(), []string{"bitcoin"})
+	if err == nil {
+		t.Error("Expected error for failed request")
+	}
+}
+
+func TestBinanceClient_GetPrices_InvalidJSON(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`invalid json`))
+	}))
+	defer testServer.Close()
+
+	client := &BinanceClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	_, err := client.GetPrices(context.Background(), []string{"bitcoin"})
+	if err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestValidateCoin_KeepsSymbolTable(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"symbol": "BTCUSDT", "price": "45000"}, {"symbol": "DOGEUSDT", "price": "0.08"}]`))
+	}))
+	defer testServer.Close()
+
+	client := &BinanceClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	// Тикер новой валюты известен только проверке, общая таблица не меняется
+	if err := ValidateCoin(context.Background(), client, models.Currency{NameCurrency: "dogecoin", Symbol: "doge"}); err != nil {
+		t.Fatalf("Expected dogecoin to be valid, got %v", err)
+	}
+	if _, ok := client.symbol(context.Background(), "dogecoin"); ok {
+		t.Error("ValidateCoin must not add symbols to the shared provider")
+	}
+
+	prices, err := client.GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if btc, ok := prices["bitcoin"]; !ok || btc.USD.String() != "45000" {
+		t.Errorf("Expected default symbols to be kept, got %+v", prices)
+	}
+}
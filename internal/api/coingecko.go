@@ -1,12 +1,14 @@
 package api
 
 import (
+	"context"
 	"cryptorate-service/internal/models"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,6 +16,10 @@ import (
 type CoinGeckoClient struct {
 	baseURL string
 	client  *http.Client
+	// retry повторы при 429 и 5xx, нулевое значение - без повторов
+	retry RetryPolicy
+	// limiter ограничение частоты запросов, nil - без ограничений
+	limiter *Limiter
 }
 
 func NewCoinGeckoClient() *CoinGeckoClient {
@@ -22,35 +28,144 @@ func NewCoinGeckoClient() *CoinGeckoClient {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		retry:   DefaultRetryPolicy,
+		limiter: NewLimiter(ProviderCoinGecko, DefaultLimits[ProviderCoinGecko], nil),
 	}
 }
 
-//Выполняет запрос курса валют по API, читает ответ, парсит JSON
-func (c *CoinGeckoClient) GetPrices(coinIDs []string) (models.CoinGeckoResponse, error) {
+// Name возвращает имя источника
+func (c *CoinGeckoClient) Name() string {
+	return ProviderCoinGecko
+}
+
+// SetLimits заменяет ограничение частоты запросов
+func (c *CoinGeckoClient) SetLimits(limits map[string]LimitConfig, budget RequestBudget) {
+	c.limiter = NewLimiter(c.Name(), limits[c.Name()], budget)
+}
+
+// Выполняет запрос курса валют по API, читает ответ, парсит JSON
+func (c *CoinGeckoClient) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
 	// Формируем URL
 	params := url.Values{}
 	params.Add("ids", strings.Join(coinIDs, ","))
-	params.Add("vs_currencies", "usd")
+	params.Add("vs_currencies", strings.Join(normalizeQuotes(quotes), ","))
+	// Рыночные показатели приходят в том же ответе: usd_market_cap, usd_24h_vol, usd_24h_change
+	params.Add("include_market_cap", "true")
+	params.Add("include_24hr_vol", "true")
+	params.Add("include_24hr_change", "true")
 	url := fmt.Sprintf("%s/simple/price?%s", c.baseURL, params.Encode())
 
-	// Выполняем запрос
-	resp, err := c.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
+	var result models.CoinGeckoResponse
+	if err := c.getJSON(ctx, url, &result); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return result, nil
+}
+
+// getJSON выполняет GET-запрос с повторами и разбирает JSON-ответ в dst.
+// Ошибки: ErrRateLimited, ErrUpstreamUnavailable, ErrBadPayload или ошибка контекста.
+func (c *CoinGeckoClient) getJSON(ctx context.Context, url string, dst interface{}) error {
+	return c.retry.do(ctx, func() error {
+		// Каждая попытка расходует лимит запросов
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		// Выполняем запрос
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return requestError(ctx, err)
+		}
+		defer resp.Body.Close()
+
+		// Читаем ответ
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return requestError(ctx, fmt.Errorf("failed to read response: %w", err))
+		}
+
+		if err := checkStatus(resp); err != nil {
+			return err
+		}
+
+		// Парсим JSON
+		if err := json.Unmarshal(body, dst); err != nil {
+			return payloadError(err)
+		}
+		return nil
+	})
+}
 
-	// Читаем ответ
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+// historyChunk максимальный период одного запроса истории. На периодах
+// до 90 дней CoinGecko отдаёт почасовые данные, на более длинных - дневные.
+const historyChunk = 90 * 24 * time.Hour
+
+type marketChartResponse struct {
+	// Пары [время в миллисекундах, цена]. Цена разбирается из текста числа,
+	// чтобы не терять точность мелких цен.
+	Prices [][2]json.Number `json:"prices"`
+}
+
+// GetHistory загружает курсы через /coins/{id}/market_chart/range.
+// Длинный период разбивается на части, чтобы сохранить почасовую детализацию.
+// API включает обе границы, поэтому все части, кроме последней, запрашиваются
+// без последней секунды: граница частей не загружается дважды.
+func (c *CoinGeckoClient) GetHistory(ctx context.Context, coinID, quote string, from, to time.Time) ([]models.PricePoint, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid period: %s - %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
 	}
+	quote = normalizeQuotes([]string{quote})[0]
 
-	// Парсим JSON
-	var result models.CoinGeckoResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	var points []models.PricePoint
+	for start := from; start.Before(to); start = start.Add(historyChunk) {
+		end := start.Add(historyChunk)
+		if end.Before(to) {
+			end = end.Add(-time.Second)
+		} else {
+			end = to
+		}
+
+		chunk, err := c.getMarketChart(ctx, coinID, quote, start, end)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, chunk...)
 	}
 
-	return result, nil
+	return points, nil
+}
+
+func (c *CoinGeckoClient) getMarketChart(ctx context.Context, coinID, quote string, from, to time.Time) ([]models.PricePoint, error) {
+	params := url.Values{}
+	params.Add("vs_currency", quote)
+	params.Add("from", strconv.FormatInt(from.Unix(), 10))
+	params.Add("to", strconv.FormatInt(to.Unix(), 10))
+	url := fmt.Sprintf("%s/coins/%s/market_chart/range?%s", c.baseURL, url.PathEscape(coinID), params.Encode())
+
+	var chart marketChartResponse
+	if err := c.getJSON(ctx, url, &chart); err != nil {
+		return nil, err
+	}
+
+	points := make([]models.PricePoint, 0, len(chart.Prices))
+	for _, p := range chart.Prices {
+		millis, err := p[0].Float64()
+		if err != nil {
+			return nil, payloadError(err)
+		}
+		price, err := models.ParseDecimal(p[1].String())
+		if err != nil {
+			return nil, payloadError(err)
+		}
+		points = append(points, models.PricePoint{
+			Time:  time.UnixMilli(int64(millis)).UTC(),
+			Price: price,
+		})
+	}
+	return points, nil
 }
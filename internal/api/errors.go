@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Ошибки источников курсов. Проверяются через errors.Is.
+var (
+	// ErrRateLimited источник ограничил частоту запросов (HTTP 429)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUpstreamUnavailable источник недоступен: сетевая ошибка или HTTP 5xx
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	// ErrBadPayload источник вернул ответ, который не удалось разобрать
+	ErrBadPayload = errors.New("bad payload")
+)
+
+// StatusError неуспешный HTTP-ответ источника.
+// RetryAfter заполняется из заголовка Retry-After, если он есть.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("API returned status %d (retry after %s)", e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("API returned status %d", e.StatusCode)
+}
+
+// Unwrap позволяет проверять статус через errors.Is(err, ErrRateLimited)
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrUpstreamUnavailable
+	}
+	return nil
+}
+
+// checkStatus возвращает *StatusError для ответа со статусом, отличным от 200
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в формате HTTP-даты
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// requestError оборачивает ошибку выполнения запроса. Если отменён ctx
+// вызывающего, ошибка возвращается как есть. Остальные ошибки, в том числе
+// истечение http.Client.Timeout, считаются недоступностью источника.
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return fmt.Errorf("%w: API request failed: %v", ErrUpstreamUnavailable, err)
+}
+
+// payloadError оборачивает ошибку разбора ответа
+func payloadError(err error) error {
+	return fmt.Errorf("%w: failed to parse JSON: %v", ErrBadPayload, err)
+}
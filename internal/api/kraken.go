@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// krakenAssets тикеры, которые на Kraken называются иначе
+var krakenAssets = map[string]string{
+	"BTC":  "XBT",
+	"DOGE": "XDG",
+}
+
+// krakenAsset возвращает название актива на Kraken (BTC -> XBT)
+func krakenAsset(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if asset, ok := krakenAssets[symbol]; ok {
+		return asset
+	}
+	return symbol
+}
+
+// KrakenClient получает спотовые курсы с биржи Kraken
+type KrakenClient struct {
+	baseURL string
+	client  *http.Client
+	limiter *Limiter
+	symbolTable
+}
+
+func NewKrakenClient() *KrakenClient {
+	return &KrakenClient{
+		baseURL: "https://api.kraken.com/0/public",
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		limiter: NewLimiter(ProviderKraken, DefaultLimits[ProviderKraken], nil),
+	}
+}
+
+// Name возвращает имя источника
+func (c *KrakenClient) Name() string {
+	return ProviderKraken
+}
+
+// SetLimits заменяет ограничение частоты запросов
+func (c *KrakenClient) SetLimits(limits map[string]LimitConfig, budget RequestBudget) {
+	c.limiter = NewLimiter(c.Name(), limits[c.Name()], budget)
+}
+
+type krakenTickerResponse struct {
+	Error  []string `json:"error"`
+	Result map[string]struct {
+		// c - цена и объём последней сделки
+		Close []string `json:"c"`
+	} `json:"result"`
+}
+
+// krakenPair возвращает название пары на Kraken (BTC, eur -> XBTEUR)
+func krakenPair(symbol, quote string) string {
+	return krakenAsset(symbol) + krakenAsset(quote)
+}
+
+// GetPrices запрашивает курс каждой пары отдельно: Kraken отклоняет весь запрос,
+// если хотя бы одна пара неизвестна. Такие валюты пропускаются.
+func (c *KrakenClient) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
+	quotes = normalizeQuotes(quotes)
+	result := make(models.CoinGeckoResponse, len(coinIDs))
+
+	var lastErr error
+	requested := 0
+	for _, id := range coinIDs {
+		symbol, ok := c.symbol(ctx, id)
+		if !ok {
+			continue
+		}
+
+		for _, quote := range quotes {
+			if krakenAsset(symbol) == krakenAsset(quote) {
+				// Котировка к самой себе всегда равна 1
+				entry := result[id]
+				entry.SetPrice(quote, models.DecimalFromInt(1))
+				result[id] = entry
+				continue
+			}
+
+			requested++
+			price, err := c.getPairPrice(ctx, krakenPair(symbol, quote))
+			if err != nil {
+				log.Printf("kraken: %s/%s skipped: %v", id, quote, err)
+				lastErr = err
+				continue
+			}
+
+			entry := result[id]
+			entry.SetPrice(quote, price)
+			result[id] = entry
+		}
+	}
+
+	// Если не удалось получить ни одного курса, считаем запрос неудачным
+	if requested > 0 && lastErr != nil && len(result) == 0 {
+		return nil, lastErr
+	}
+
+	return result, nil
+}
+
+func (c *KrakenClient) getPairPrice(ctx context.Context, pair string) (models.Decimal, error) {
+	params := url.Values{}
+	params.Add("pair", pair)
+	url := fmt.Sprintf("%s/Ticker?%s", c.baseURL, params.Encode())
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return models.Decimal{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return models.Decimal{}, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return models.Decimal{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return models.Decimal{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := checkStatus(resp); err != nil {
+		return models.Decimal{}, err
+	}
+
+	var ticker krakenTickerResponse
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return models.Decimal{}, payloadError(err)
+	}
+
+	if len(ticker.Error) > 0 {
+		return models.Decimal{}, fmt.Errorf("API error for %s: %v", pair, ticker.Error)
+	}
+
+	// В ответе одна пара, но под каноническим именем (XBTUSD -> XXBTZUSD)
+	for _, data := range ticker.Result {
+		if len(data.Close) == 0 {
+			break
+		}
+		price, err := models.ParseDecimal(data.Close[0])
+		if err != nil {
+			return models.Decimal{}, fmt.Errorf("%w: invalid price %q for %s: %v", ErrBadPayload, data.Close[0], pair, err)
+		}
+		return price, nil
+	}
+
+	return models.Decimal{}, fmt.Errorf("no ticker data for %s", pair)
+}
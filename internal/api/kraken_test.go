@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKrakenClient_GetPrices(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Query().Get("pair") {
+		case "XBTUSD":
+			w.Write([]byte(`{"error": [], "result": {"XXBTZUSD": {"c": ["45000.50000", "0.001"]}}}`))
+		case "ETHUSD":
+			w.Write([]byte(`{"error": [], "result": {"XETHZUSD": {"c": ["2500.75000", "0.5"]}}}`))
+		default:
+			w.Write([]byte(`{"error": ["EQuery:Unknown asset pair"]}`))
+		}
+	}))
+	defer testServer.Close()
+
+	client := &KrakenClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	prices, err := client.GetPrices(context.Background(), []string{"bitcoin", "ethereum", "binancecoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if len(prices) != 2 {
+		t.Errorf("Expected 2 prices, got %d", len(prices))
+	}
+
+	if btc, ok := prices["bitcoin"]; !ok || btc.USD.String() != "45000.5" {
+		t.Errorf("Bitcoin price incorrect. Got %+v", btc)
+	}
+
+	if eth, ok := prices["ethereum"]; !ok || eth.USD.String() != "2500.75" {
+		t.Errorf("Ethereum price incorrect. Got %+v", eth)
+	}
+}
+
+func TestKrakenClient_GetPrices_AllFailed(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer testServer.Close()
+
+	client := &KrakenClient{
+		baseURL: testServer.URL,
+		client:  testServer.Client(),
+	}
+
+	_, err := client.GetPrices(context.Background(), []string{"bitcoin", "ethereum"})
+	if err == nil {
+		t.Error("Expected error when every pair failed")
+	}
+}
+
+func TestKrakenPair(t *testing.T) {
+	testCases := []struct {
+		symbol string
+		quote  string
+		want   string
+	}{
+		{"BTC", "usd", "XBTUSD"},
+		{"DOGE", "usd", "XDGUSD"},
+		{"ETH", "eur", "ETHEUR"},
+		{"ETH", "btc", "ETHXBT"},
+	}
+
+	for _, tc := range testCases {
+		if got := krakenPair(tc.symbol, tc.quote); got != tc.want {
+			t.Errorf("krakenPair(%s, %s) = %s, want %s", tc.symbol, tc.quote, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LimitConfig ограничение частоты запросов к источнику
+type LimitConfig struct {
+	// PerMinute допустимое число запросов в минуту. Ноль - без ограничений.
+	PerMinute int
+	// Burst сколько запросов можно сделать подряд без ожидания
+	Burst int
+}
+
+// DefaultLimits ограничения бесплатных тарифов публичных API
+var DefaultLimits = map[string]LimitConfig{
+	ProviderCoinGecko: {PerMinute: 30, Burst: 5},
+	ProviderBinance:   {PerMinute: 1200, Burst: 20},
+	ProviderKraken:    {PerMinute: 60, Burst: 15},
+}
+
+// ParseLimits разбирает ограничения вида coingecko=30,kraken=60:10
+// (запросов в минуту и, через двоеточие, размер пачки).
+// Источники, не указанные в spec, получают ограничения по умолчанию.
+func ParseLimits(spec string) (map[string]LimitConfig, error) {
+	limits := make(map[string]LimitConfig, len(DefaultLimits))
+	for name, limit := range DefaultLimits {
+		limits[name] = limit
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: expected provider=per_minute[:burst]", item)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		perMinuteSpec, burstSpec, hasBurst := strings.Cut(value, ":")
+		perMinute, err := strconv.Atoi(strings.TrimSpace(perMinuteSpec))
+		if err != nil || perMinute < 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: bad requests per minute", item)
+		}
+
+		limit := LimitConfig{PerMinute: perMinute, Burst: limits[name].Burst}
+		if hasBurst {
+			limit.Burst, err = strconv.Atoi(strings.TrimSpace(burstSpec))
+			if err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid rate limit %q: bad burst", item)
+			}
+		}
+		limits[name] = limit
+	}
+
+	return limits, nil
+}
+
+// RequestBudget общий для нескольких процессов счётчик запросов к источнику.
+// Реализуется репозиторием: бот, воркер и backfill расходуют один бюджет.
+type RequestBudget interface {
+	// ConsumeRequestBudget учитывает запрос в минутном окне windowStart.
+	// Возвращает false, если в этом окне уже сделано limit запросов.
+	ConsumeRequestBudget(ctx context.Context, provider string, windowStart time.Time, limit int) (bool, error)
+}
+
+// Limiter ограничивает частоту запросов к источнику: локальный token bucket
+// сглаживает запросы процесса, общий бюджет в БД ограничивает все процессы вместе.
+// Нулевой указатель означает отсутствие ограничений.
+type Limiter struct {
+	provider  string
+	perMinute int
+	bucket    *tokenBucket
+	budget    RequestBudget
+}
+
+// NewLimiter создаёт ограничитель для источника. Возвращает nil,
+// если ограничение не задано. budget может быть nil.
+func NewLimiter(provider string, limit LimitConfig, budget RequestBudget) *Limiter {
+	if limit.PerMinute <= 0 {
+		return nil
+	}
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		provider:  provider,
+		perMinute: limit.PerMinute,
+		bucket:    newTokenBucket(float64(limit.PerMinute)/60, burst),
+		budget:    budget,
+	}
+}
+
+// Wait блокирует до момента, когда можно выполнить запрос.
+// Ошибка возвращается только при отмене контекста.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	if delay := l.bucket.reserve(time.Now()); delay > 0 {
+		if err := sleep(ctx, delay); err != nil {
+			l.bucket.refund()
+			return err
+		}
+	}
+
+	if l.budget == nil {
+		return nil
+	}
+
+	for {
+		now := time.Now().UTC()
+		window := now.Truncate(time.Minute)
+		ok, err := l.budget.ConsumeRequestBudget(ctx, l.provider, window, l.perMinute)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// Недоступность БД не должна останавливать загрузку курсов,
+			// локальный ограничитель продолжает работать
+			log.Printf("limiter: %s budget unavailable: %v", l.provider, err)
+			return nil
+		}
+		if ok {
+			return nil
+		}
+		if err := sleep(ctx, window.Add(time.Minute).Sub(now)); err != nil {
+			return err
+		}
+	}
+}
+
+// LimitSetter реализуют источники, которые обращаются к внешним API
+type LimitSetter interface {
+	SetLimits(limits map[string]LimitConfig, budget RequestBudget)
+}
+
+// SetLimits настраивает ограничения источника. Вызывается до первого запроса.
+// Для источников без внешних запросов ничего не делает.
+func SetLimits(provider PriceProvider, limits map[string]LimitConfig, budget RequestBudget) {
+	if setter, ok := provider.(LimitSetter); ok {
+		setter.SetLimits(limits, budget)
+	}
+}
+
+// tokenBucket классический token bucket. Запрос может уйти в долг:
+// reserve возвращает время, через которое взятый токен станет доступен.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // токенов в секунду
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund возвращает токен, если запрос так и не был выполнен
+func (b *tokenBucket) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+// sleep ждёт d или отмены контекста
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
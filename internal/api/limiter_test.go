@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeBudget бюджет запросов в памяти без учёта окон
+type fakeBudget struct {
+	remaining int
+	err       error
+}
+
+func (b *fakeBudget) ConsumeRequestBudget(ctx context.Context, provider string, windowStart time.Time, limit int) (bool, error) {
+	if b.err != nil {
+		return false, b.err
+	}
+	if b.remaining == 0 {
+		return false, nil
+	}
+	b.remaining--
+	return true, nil
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("coingecko=10, kraken=120:30, custom=5")
+	if err != nil {
+		t.Fatalf("ParseLimits failed: %v", err)
+	}
+
+	want := map[string]LimitConfig{
+		ProviderCoinGecko: {PerMinute: 10, Burst: 5},
+		ProviderBinance:   DefaultLimits[ProviderBinance],
+		ProviderKraken:    {PerMinute: 120, Burst: 30},
+		"custom":          {PerMinute: 5},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("Expected %v, got %v", want, limits)
+	}
+
+	if limits, err := ParseLimits(""); err != nil || !reflect.DeepEqual(limits, DefaultLimits) {
+		t.Errorf("Expected default limits, got %v, %v", limits, err)
+	}
+
+	for _, spec := range []string{"coingecko", "coingecko=fast", "coingecko=-1", "coingecko=10:0"} {
+		if _, err := ParseLimits(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(1, 2)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Пачка из burst запросов проходит сразу
+	if d := bucket.reserve(now); d != 0 {
+		t.Errorf("Expected no delay, got %s", d)
+	}
+	if d := bucket.reserve(now); d != 0 {
+		t.Errorf("Expected no delay, got %s", d)
+	}
+
+	// Следующий ждёт пополнения
+	if d := bucket.reserve(now); d != time.Second {
+		t.Errorf("Expected 1s delay, got %s", d)
+	}
+
+	// Через 3 секунды накапливается не больше burst
+	later := now.Add(3 * time.Second)
+	for i := 0; i < 2; i++ {
+		if d := bucket.reserve(later); d != 0 {
+			t.Errorf("Expected no delay after refill, got %s", d)
+		}
+	}
+	if d := bucket.reserve(later); d == 0 {
+		t.Error("Expected delay after burst is used")
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	var nilLimiter *Limiter
+	if err := nilLimiter.Wait(context.Background()); err != nil {
+		t.Errorf("Nil limiter should not block: %v", err)
+	}
+
+	if NewLimiter(ProviderCoinGecko, LimitConfig{}, nil) != nil {
+		t.Error("Expected nil limiter without limit")
+	}
+
+	budget := &fakeBudget{remaining: 1}
+	limiter := NewLimiter(ProviderCoinGecko, LimitConfig{PerMinute: 600, Burst: 10}, budget)
+
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	// Бюджет исчерпан другими процессами: ждём до отмены контекста
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Ошибка БД не блокирует запросы
+	budget.err = errors.New("connection refused")
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("Expected fail-open on budget error, got %v", err)
+	}
+}
+
+func TestLimiter_WaitCancelRefundsToken(t *testing.T) {
+	limiter := NewLimiter(ProviderCoinGecko, LimitConfig{PerMinute: 1, Burst: 1}, nil)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	limiter.bucket.mu.Lock()
+	tokens := limiter.bucket.tokens
+	limiter.bucket.mu.Unlock()
+	if tokens < -0.01 {
+		t.Errorf("Expected cancelled reservation to be refunded, tokens = %f", tokens)
+	}
+}
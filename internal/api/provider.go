@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriceProvider источник курсов криптовалют.
+// Воркер и бот работают только через этот интерфейс, поэтому
+// источник можно заменить без изменения кода.
+type PriceProvider interface {
+	// Name возвращает имя источника (coingecko, static, ...)
+	Name() string
+	// GetPrices возвращает курсы для переданных идентификаторов валют (bitcoin, ethereum)
+	// в указанных валютах котировки (usd, eur, btc). Без котировок используется usd.
+	GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error)
+}
+
+// Имена поддерживаемых источников
+const (
+	ProviderCoinGecko = "coingecko"
+	ProviderBinance   = "binance"
+	ProviderKraken    = "kraken"
+	ProviderStatic    = "static"
+)
+
+// NewProvider создаёт источник курсов по имени.
+// Пустое имя означает источник по умолчанию (CoinGecko).
+func NewProvider(name string) (PriceProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", ProviderCoinGecko:
+		return NewCoinGeckoClient(), nil
+	case ProviderBinance:
+		return NewBinanceClient(), nil
+	case ProviderKraken:
+		return NewKrakenClient(), nil
+	case ProviderStatic:
+		return NewStaticProvider(defaultStaticPrices), nil
+	default:
+		return nil, fmt.Errorf("unknown price provider: %s", name)
+	}
+}
+
+// ValidateCoin проверяет, что источник знает валюту и возвращает по ней курс.
+// Используется перед добавлением валюты в таблицу Currency.
+// Тикер валюты передаётся только в этот запрос: источник общий
+// для всех запросов админского API, его таблица тикеров не меняется.
+func ValidateCoin(ctx context.Context, provider PriceProvider, currency models.Currency) error {
+	if currency.Symbol != "" {
+		ctx = WithSymbols(ctx, map[string]string{
+			currency.NameCurrency: strings.ToUpper(currency.Symbol),
+		})
+	}
+
+	prices, err := provider.GetPrices(ctx, []string{currency.NameCurrency})
+	if err != nil {
+		return fmt.Errorf("provider %s: %w", provider.Name(), err)
+	}
+
+	if price, ok := prices[currency.NameCurrency].Price(models.DefaultQuote); !ok || price.Sign() <= 0 {
+		return fmt.Errorf("provider %s does not know coin %q", provider.Name(), currency.NameCurrency)
+	}
+	return nil
+}
+
+// ParseQuotes разбирает список валют котировки через запятую (usd,eur,rub)
+func ParseQuotes(spec string) []string {
+	return normalizeQuotes(strings.Split(spec, ","))
+}
+
+// normalizeQuotes приводит котировки к нижнему регистру и убирает повторы.
+// Пустой список заменяется на котировку по умолчанию.
+func normalizeQuotes(quotes []string) []string {
+	seen := make(map[string]bool, len(quotes))
+	result := make([]string, 0, len(quotes))
+	for _, quote := range quotes {
+		quote = strings.ToLower(strings.TrimSpace(quote))
+		if quote == "" || seen[quote] {
+			continue
+		}
+		seen[quote] = true
+		result = append(result, quote)
+	}
+
+	if len(result) == 0 {
+		return []string{models.DefaultQuote}
+	}
+	return result
+}
+
+// NewProviders создаёт источник по списку имён через запятую (coingecko,binance,kraken).
+// Если указано несколько источников, курсы агрегируются с отбрасыванием выбросов.
+func NewProviders(spec string, maxDeviation float64) (PriceProvider, error) {
+	// Пустые элементы ("coingecko,") пропускаются: пустое имя означает
+	// CoinGecko, и медиана учла бы один источник дважды
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate price provider: %s", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) <= 1 {
+		return NewProvider(strings.Join(names, ""))
+	}
+
+	providers := make([]PriceProvider, 0, len(names))
+	for _, name := range names {
+		provider, err := NewProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return NewAggregator(providers, maxDeviation), nil
+}
+
+// defaultStaticPrices курсы для локальной разработки без доступа к внешним API
+var defaultStaticPrices = map[string]float64{
+	"bitcoin":     45000.00,
+	"ethereum":    2500.00,
+	"tether":      1.00,
+	"binancecoin": 300.00,
+	"solana":      100.00,
+	"ripple":      0.60,
+	"cardano":     0.50,
+}
+
+// staticQuoteRates курсы фиатных валют к доллару для StaticProvider
+var staticQuoteRates = map[string]models.Decimal{
+	"usd": models.DecimalFromInt(1),
+	"eur": models.MustParseDecimal("0.92"),
+	"rub": models.DecimalFromInt(92),
+}
+
+// StaticProvider возвращает заранее заданные курсы в долларах.
+// Другие валюты котировки пересчитываются по фиксированному курсу.
+// Используется локально и в тестах вместо реального API.
+type StaticProvider struct {
+	prices map[string]models.Decimal
+}
+
+// NewStaticProvider принимает цены в долларах. Каждая цена хранится
+// как кратчайшая десятичная запись числа: 0.6 остаётся 0.6.
+func NewStaticProvider(prices map[string]float64) *StaticProvider {
+	copied := make(map[string]models.Decimal, len(prices))
+	for id, price := range prices {
+		decimal, err := models.DecimalFromFloat(price)
+		if err != nil {
+			continue
+		}
+		copied[id] = decimal
+	}
+	return &StaticProvider{prices: copied}
+}
+
+func (p *StaticProvider) Name() string {
+	return ProviderStatic
+}
+
+// GetPrices возвращает курсы только для известных валют и котировок, остальные пропускает
+func (p *StaticProvider) GetPrices(ctx context.Context, coinIDs []string, quotes ...string) (models.CoinGeckoResponse, error) {
+	quotes = normalizeQuotes(quotes)
+
+	result := make(models.CoinGeckoResponse, len(coinIDs))
+	for _, id := range coinIDs {
+		price, ok := p.prices[id]
+		if !ok {
+			continue
+		}
+
+		var entry models.PriceQuote
+		for _, quote := range quotes {
+			rate, ok := staticQuoteRates[quote]
+			if !ok {
+				continue
+			}
+			entry.SetPrice(quote, price.Mul(rate))
+		}
+		if len(entry.Prices) > 0 {
+			result[id] = entry
+		}
+	}
+	return result, nil
+}
+
+// SymbolSetter реализуют источники, которые запрашивают курсы по тикеру (BTC, ETH),
+// а не по идентификатору CoinGecko. Биржам нужно знать соответствие bitcoin -> BTC.
+type SymbolSetter interface {
+	SetSymbols(symbols map[string]string)
+}
+
+// SetSymbols передаёт источнику тикеры валют из таблицы Currency.
+// Для источников, которым тикеры не нужны, ничего не делает.
+func SetSymbols(provider PriceProvider, currencies []models.Currency) {
+	setter, ok := provider.(SymbolSetter)
+	if !ok {
+		return
+	}
+
+	symbols := make(map[string]string, len(currencies))
+	for _, currency := range currencies {
+		if currency.Symbol == "" {
+			continue
+		}
+		symbols[currency.NameCurrency] = strings.ToUpper(currency.Symbol)
+	}
+	setter.SetSymbols(symbols)
+}
+
+// defaultSymbols тикеры валют из начальной миграции, используются пока не вызван SetSymbols
+var defaultSymbols = map[string]string{
+	"bitcoin":     "BTC",
+	"ethereum":    "ETH",
+	"tether":      "USDT",
+	"binancecoin": "BNB",
+	"solana":      "SOL",
+	"ripple":      "XRP",
+	"cardano":     "ADA",
+}
+
+// symbolTable потокобезопасное соответствие идентификатора валюты и тикера.
+// Нулевое значение готово к использованию и содержит тикеры по умолчанию.
+type symbolTable struct {
+	mu      sync.RWMutex
+	symbols map[string]string
+}
+
+// SetSymbols заменяет таблицу тикеров
+func (t *symbolTable) SetSymbols(symbols map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.symbols = symbols
+}
+
+// symbolsKey ключ контекста с тикерами одного запроса
+type symbolsKey struct{}
+
+// WithSymbols возвращает контекст, в котором источники ищут тикер валюты
+// сначала в symbols, затем в своей таблице. Позволяет запросить курс валюты,
+// которой ещё нет в таблице тикеров, не изменяя общий источник.
+func WithSymbols(ctx context.Context, symbols map[string]string) context.Context {
+	return context.WithValue(ctx, symbolsKey{}, symbols)
+}
+
+func (t *symbolTable) symbol(ctx context.Context, coinID string) (string, bool) {
+	if symbols, ok := ctx.Value(symbolsKey{}).(map[string]string); ok {
+		if symbol, ok := symbols[coinID]; ok {
+			return symbol, true
+		}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	symbols := t.symbols
+	if symbols == nil {
+		symbols = defaultSymbols
+	}
+	symbol, ok := symbols[coinID]
+	return symbol, ok
+}
+
+// HistoryProvider реализуют источники, умеющие отдавать курсы за прошлые периоды.
+// Используется командой save backfill для заполнения истории.
+type HistoryProvider interface {
+	// GetHistory возвращает курсы валюты в котировке quote за период [from, to]
+	// в порядке возрастания времени
+	GetHistory(ctx context.Context, coinID, quote string, from, to time.Time) ([]models.PricePoint, error)
+}
+
+// Проверка на этапе компиляции, что клиенты реализуют интерфейс
+var (
+	_ PriceProvider = (*CoinGeckoClient)(nil)
+	_ PriceProvider = (*BinanceClient)(nil)
+	_ PriceProvider = (*KrakenClient)(nil)
+	_ PriceProvider = (*StaticProvider)(nil)
+	_ PriceProvider = (*Aggregator)(nil)
+	_ SymbolSetter  = (*BinanceClient)(nil)
+	_ SymbolSetter  = (*KrakenClient)(nil)
+	_ SymbolSetter  = (*Aggregator)(nil)
+
+	_ LimitSetter = (*CoinGeckoClient)(nil)
+	_ LimitSetter = (*BinanceClient)(nil)
+	_ LimitSetter = (*KrakenClient)(nil)
+	_ LimitSetter = (*Aggregator)(nil)
+
+	_ HistoryProvider = (*CoinGeckoClient)(nil)
+	_ HistoryProvider = (*Aggregator)(nil)
+)
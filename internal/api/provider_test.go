@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		wantName string
+		wantErr  bool
+	}{
+		{"Default", "", ProviderCoinGecko, false},
+		{"CoinGecko", "coingecko", ProviderCoinGecko, false},
+		{"Case insensitive", " CoinGecko ", ProviderCoinGecko, false},
+		{"Binance", "binance", ProviderBinance, false},
+		{"Kraken", "kraken", ProviderKraken, false},
+		{"Static", "static", ProviderStatic, false},
+		{"Unknown", "unknown", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := NewProvider(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for provider %q", tc.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if provider.Name() != tc.wantName {
+				t.Errorf("Expected provider %s, got %s", tc.wantName, provider.Name())
+			}
+		})
+	}
+}
+
+func TestStaticProvider_GetPrices(t *testing.T) {
+	provider := NewStaticProvider(map[string]float64{
+		"bitcoin":  45000.50,
+		"ethereum": 2500.75,
+	})
+
+	prices, err := provider.GetPrices(context.Background(), []string{"bitcoin", "unknown"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if len(prices) != 1 {
+		t.Errorf("Expected 1 price, got %d", len(prices))
+	}
+
+	if btc, ok := prices["bitcoin"]; !ok || btc.USD.String() != "45000.5" {
+		t.Errorf("Bitcoin price incorrect. Got %+v", btc)
+	}
+
+	if _, ok := prices["unknown"]; ok {
+		t.Error("Unknown coin should be skipped")
+	}
+}
+
+func TestStaticProvider_GetPrices_Quotes(t *testing.T) {
+	provider := NewStaticProvider(map[string]float64{"bitcoin": 100})
+
+	prices, err := provider.GetPrices(context.Background(), []string{"bitcoin"}, "eur", "unknown")
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	if eur, ok := prices["bitcoin"].Price("eur"); !ok || eur.String() != "92" {
+		t.Errorf("Bitcoin EUR price incorrect. Got %v", eur)
+	}
+
+	if _, ok := prices["bitcoin"].Price("unknown"); ok {
+		t.Error("Unknown quote should be skipped")
+	}
+}
+
+func TestParseQuotes(t *testing.T) {
+	quotes := ParseQuotes(" USD,eur,,usd ")
+	if len(quotes) != 2 || quotes[0] != "usd" || quotes[1] != "eur" {
+		t.Errorf("Unexpected quotes: %v", quotes)
+	}
+
+	if quotes := ParseQuotes(""); len(quotes) != 1 || quotes[0] != "usd" {
+		t.Errorf("Expected default quote, got %v", quotes)
+	}
+}
+
+func TestValidateCoin(t *testing.T) {
+	provider := NewStaticProvider(map[string]float64{"bitcoin": 45000})
+
+	if err := ValidateCoin(context.Background(), provider, models.Currency{NameCurrency: "bitcoin", Symbol: "BTC"}); err != nil {
+		t.Errorf("Expected bitcoin to be valid, got %v", err)
+	}
+
+	if err := ValidateCoin(context.Background(), provider, models.Currency{NameCurrency: "notacoin", Symbol: "NAC"}); err == nil {
+		t.Error("Expected error for unknown coin")
+	}
+}
@@ -0,0 +1,348 @@
+package rest
+
+import (
+	"context"
+	"crypto/subtle"
+	"cryptorate-service/internal/api"
+	"cryptorate-service/internal/models"
+	"cryptorate-service/internal/repository"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminRepositoryInterface операции репозитория для управления валютами
+type AdminRepositoryInterface interface {
+	CreateCurrency(ctx context.Context, currency models.Currency) (models.Currency, error)
+	UpdateCurrency(ctx context.Context, id int, update models.CurrencyUpdate) (models.Currency, error)
+	DeleteCurrency(ctx context.Context, id int) error
+	GetCurrencyByID(ctx context.Context, id int) (models.Currency, error)
+	GetFetchRuns(ctx context.Context, limit int) ([]models.FetchRun, error)
+	GetLastSuccessfulFetch(ctx context.Context) (time.Time, error)
+}
+
+// AdminHandler обрабатывает запросы /api/v1/admin.
+// Провайдер используется только для проверки новых валют.
+type AdminHandler struct {
+	repo     AdminRepositoryInterface
+	provider api.PriceProvider
+}
+
+func NewAdminHandler(repo AdminRepositoryInterface, provider api.PriceProvider) *AdminHandler {
+	return &AdminHandler{repo: repo, provider: provider}
+}
+
+// CurrencyRequest тело запроса на создание валюты
+type CurrencyRequest struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Symbol      string `json:"symbol"`
+	IsTracked   *bool  `json:"is_tracked"`
+	// MaxAgeSeconds допустимый возраст курса, без поля - значение по умолчанию
+	MaxAgeSeconds *int64 `json:"max_age_seconds"`
+}
+
+// CurrencyPatchRequest тело запроса на изменение валюты, отсутствующие поля не меняются
+type CurrencyPatchRequest struct {
+	Name        *string `json:"name"`
+	DisplayName *string `json:"display_name"`
+	Symbol      *string `json:"symbol"`
+	IsTracked   *bool   `json:"is_tracked"`
+	// MaxAgeSeconds 0 возвращает допустимый возраст курса по умолчанию
+	MaxAgeSeconds *int64 `json:"max_age_seconds"`
+}
+
+// IngestionResponse журнал циклов загрузки курсов.
+// LastSuccessAt - время последнего цикла, сохранившего хотя бы один курс.
+type IngestionResponse struct {
+	LastSuccessAt       *time.Time        `json:"last_success_at"`
+	SecondsSinceSuccess *int64            `json:"seconds_since_success"`
+	Runs                []models.FetchRun `json:"runs"`
+}
+
+// Допустимый возраст курса, который можно задать валюте
+const (
+	minMaxAge = time.Minute
+	maxMaxAge = 7 * 24 * time.Hour
+)
+
+// Размер журнала в ответе /admin/ingestion
+const (
+	defaultIngestionLimit = 50
+	maxIngestionLimit     = 500
+)
+
+var (
+	// coinIDPattern идентификатор валюты в CoinGecko (bitcoin, usd-coin)
+	coinIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+	// symbolPattern тикер валюты (BTC, USDT)
+	symbolPattern = regexp.MustCompile(`^[A-Z0-9]{1,10}$`)
+)
+
+// AdminAuth пропускает только запросы с заголовком Authorization: Bearer <token>.
+// Если токен не задан, админские маршруты отключены.
+func AdminAuth(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				sendError(w, "Admin API is disabled", http.StatusForbidden)
+				return
+			}
+
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				sendError(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CreateCurrency добавляет валюту после проверки через источник курсов
+func (h *AdminHandler) CreateCurrency(w http.ResponseWriter, r *http.Request) {
+	var req CurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	currency := models.Currency{
+		NameCurrency: strings.ToLower(strings.TrimSpace(req.Name)),
+		DisplayName:  strings.TrimSpace(req.DisplayName),
+		Symbol:       strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		IsTracked:    true,
+	}
+	if req.IsTracked != nil {
+		currency.IsTracked = *req.IsTracked
+	}
+	if currency.DisplayName == "" {
+		currency.DisplayName = currency.Symbol
+	}
+	if req.MaxAgeSeconds != nil {
+		if !maxAgeInRange(*req.MaxAgeSeconds) {
+			sendError(w, "Invalid max_age_seconds: expected 60-604800", http.StatusBadRequest)
+			return
+		}
+		currency.MaxAge = time.Duration(*req.MaxAgeSeconds) * time.Second
+	}
+
+	if msg := validateCurrency(currency); msg != "" {
+		sendError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.ValidateCoin(r.Context(), h.provider, currency); err != nil {
+		sendError(w, "Coin is not available from the price provider", http.StatusUnprocessableEntity)
+		return
+	}
+
+	created, err := h.repo.CreateCurrency(r.Context(), currency)
+	if errors.Is(err, repository.ErrConflict) {
+		sendError(w, "Currency already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendError(w, "Failed to create currency", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	sendJSON(w, Response{
+		Success: true,
+		Data:    toCurrencyResponse(created),
+		Meta:    &Meta{Timestamp: time.Now().Format(time.RFC3339), Version: "1.0"},
+	})
+}
+
+// UpdateCurrency изменяет валюту. При смене идентификатора или тикера
+// валюта повторно проверяется через источник курсов.
+func (h *AdminHandler) UpdateCurrency(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendError(w, "Invalid currency id", http.StatusBadRequest)
+		return
+	}
+
+	var req CurrencyPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.repo.GetCurrencyByID(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		sendError(w, "Currency not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendError(w, "Failed to get currency", http.StatusInternalServerError)
+		return
+	}
+
+	var update models.CurrencyUpdate
+	updated := current
+	if req.Name != nil {
+		name := strings.ToLower(strings.TrimSpace(*req.Name))
+		update.NameCurrency = &name
+		updated.NameCurrency = name
+	}
+	if req.DisplayName != nil {
+		displayName := strings.TrimSpace(*req.DisplayName)
+		update.DisplayName = &displayName
+		updated.DisplayName = displayName
+	}
+	if req.Symbol != nil {
+		symbol := strings.ToUpper(strings.TrimSpace(*req.Symbol))
+		update.Symbol = &symbol
+		updated.Symbol = symbol
+	}
+	update.IsTracked = req.IsTracked
+	if req.MaxAgeSeconds != nil {
+		if *req.MaxAgeSeconds != 0 && !maxAgeInRange(*req.MaxAgeSeconds) {
+			sendError(w, "Invalid max_age_seconds: expected 60-604800 or 0 for default", http.StatusBadRequest)
+			return
+		}
+		maxAge := time.Duration(*req.MaxAgeSeconds) * time.Second
+		update.MaxAge = &maxAge
+	}
+
+	if msg := validateCurrency(updated); msg != "" {
+		sendError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if updated.NameCurrency != current.NameCurrency || updated.Symbol != current.Symbol {
+		if err := api.ValidateCoin(r.Context(), h.provider, updated); err != nil {
+			sendError(w, "Coin is not available from the price provider", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	result, err := h.repo.UpdateCurrency(r.Context(), id, update)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		sendError(w, "Currency not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrConflict):
+		sendError(w, "Currency already exists", http.StatusConflict)
+		return
+	case err != nil:
+		sendError(w, "Failed to update currency", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, Response{
+		Success: true,
+		Data:    toCurrencyResponse(result),
+		Meta:    &Meta{Timestamp: time.Now().Format(time.RFC3339), Version: "1.0"},
+	})
+}
+
+// DeleteCurrency мягко удаляет валюту, история курсов сохраняется
+func (h *AdminHandler) DeleteCurrency(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendError(w, "Invalid currency id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.repo.DeleteCurrency(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		sendError(w, "Currency not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendError(w, "Failed to delete currency", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetIngestion возвращает последние циклы загрузки курсов (?limit=50)
+// и время последней успешной загрузки для алертов на пропуски
+func (h *AdminHandler) GetIngestion(w http.ResponseWriter, r *http.Request) {
+	limit := defaultIngestionLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxIngestionLimit {
+			sendError(w, "Invalid limit: expected 1-500", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.repo.GetFetchRuns(r.Context(), limit)
+	if err != nil {
+		sendError(w, "Failed to get ingestion runs", http.StatusInternalServerError)
+		return
+	}
+
+	response := IngestionResponse{Runs: runs}
+	if response.Runs == nil {
+		response.Runs = []models.FetchRun{}
+	}
+
+	lastSuccess, err := h.repo.GetLastSuccessfulFetch(r.Context())
+	switch {
+	case err == nil:
+		seconds := int64(time.Since(lastSuccess).Seconds())
+		response.LastSuccessAt = &lastSuccess
+		response.SecondsSinceSuccess = &seconds
+	case !errors.Is(err, repository.ErrNotFound):
+		sendError(w, "Failed to get ingestion runs", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, Response{
+		Success: true,
+		Data:    response,
+		Meta:    &Meta{Timestamp: time.Now().Format(time.RFC3339), Version: "1.0"},
+	})
+}
+
+// validateCurrency возвращает текст ошибки или пустую строку
+func validateCurrency(currency models.Currency) string {
+	switch {
+	case !coinIDPattern.MatchString(currency.NameCurrency):
+		return "Invalid name: expected CoinGecko coin id (e.g. bitcoin)"
+	case !symbolPattern.MatchString(currency.Symbol):
+		return "Invalid symbol: expected 1-10 letters or digits (e.g. BTC)"
+	case currency.DisplayName == "" || len(currency.DisplayName) > 50:
+		return "Invalid display_name: expected 1-50 characters"
+	}
+	return ""
+}
+
+func toCurrencyResponse(currency models.Currency) CurrencyResponse {
+	return CurrencyResponse{
+		ID:            currency.ID,
+		Name:          currency.NameCurrency,
+		DisplayName:   currency.DisplayName,
+		Symbol:        currency.Symbol,
+		IsTracked:     currency.IsTracked,
+		MaxAgeSeconds: int64(maxAgeOrDefault(currency.MaxAge).Seconds()),
+	}
+}
+
+// maxAgeInRange проверяет допустимый возраст курса в секундах до перевода
+// в time.Duration: при умножении большие значения переполнились бы
+func maxAgeInRange(seconds int64) bool {
+	return seconds >= int64(minMaxAge/time.Second) && seconds <= int64(maxMaxAge/time.Second)
+}
+
+func maxAgeOrDefault(maxAge time.Duration) time.Duration {
+	if maxAge <= 0 {
+		return models.DefaultMaxAge
+	}
+	return maxAge
+}
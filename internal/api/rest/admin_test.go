@@ -0,0 +1,317 @@
+package rest
+
+import (
+	"context"
+	"cryptorate-service/internal/api"
+	"cryptorate-service/internal/models"
+	"cryptorate-service/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// MockAdminRepository хранит валюты в памяти для тестов админского API
+type MockAdminRepository struct {
+	currencies  map[int]models.Currency
+	nextID      int
+	runs        []models.FetchRun
+	lastSuccess time.Time
+}
+
+func newMockAdminRepository() *MockAdminRepository {
+	return &MockAdminRepository{
+		currencies: map[int]models.Currency{
+			1: {ID: 1, NameCurrency: "bitcoin", DisplayName: "Bitcoin", Symbol: "BTC", IsTracked: true},
+		},
+		nextID: 2,
+	}
+}
+
+func (m *MockAdminRepository) CreateCurrency(ctx context.Context, currency models.Currency) (models.Currency, error) {
+	for _, existing := range m.currencies {
+		if existing.NameCurrency == currency.NameCurrency {
+			return models.Currency{}, repository.ErrConflict
+		}
+	}
+	currency.ID = m.nextID
+	m.nextID++
+	m.currencies[currency.ID] = currency
+	return currency, nil
+}
+
+func (m *MockAdminRepository) UpdateCurrency(ctx context.Context, id int, update models.CurrencyUpdate) (models.Currency, error) {
+	currency, ok := m.currencies[id]
+	if !ok {
+		return models.Currency{}, repository.ErrNotFound
+	}
+	if update.NameCurrency != nil {
+		currency.NameCurrency = *update.NameCurrency
+	}
+	if update.DisplayName != nil {
+		currency.DisplayName = *update.DisplayName
+	}
+	if update.Symbol != nil {
+		currency.Symbol = *update.Symbol
+	}
+	if update.IsTracked != nil {
+		currency.IsTracked = *update.IsTracked
+	}
+	m.currencies[id] = currency
+	return currency, nil
+}
+
+func (m *MockAdminRepository) DeleteCurrency(ctx context.Context, id int) error {
+	if _, ok := m.currencies[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.currencies, id)
+	return nil
+}
+
+func (m *MockAdminRepository) GetCurrencyByID(ctx context.Context, id int) (models.Currency, error) {
+	currency, ok := m.currencies[id]
+	if !ok {
+		return models.Currency{}, repository.ErrNotFound
+	}
+	return currency, nil
+}
+
+func (m *MockAdminRepository) GetFetchRuns(ctx context.Context, limit int) ([]models.FetchRun, error) {
+	if len(m.runs) > limit {
+		return m.runs[:limit], nil
+	}
+	return m.runs, nil
+}
+
+func (m *MockAdminRepository) GetLastSuccessfulFetch(ctx context.Context) (time.Time, error) {
+	if m.lastSuccess.IsZero() {
+		return time.Time{}, repository.ErrNotFound
+	}
+	return m.lastSuccess, nil
+}
+
+func newTestAdminRouter(repo *MockAdminRepository) http.Handler {
+	provider := api.NewStaticProvider(map[string]float64{"bitcoin": 45000, "solana": 100, "dogecoin": 0.08})
+	handler := NewAdminHandler(repo, provider)
+
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(AdminAuth("secret"))
+	admin.HandleFunc("/currencies", handler.CreateCurrency).Methods("POST")
+	admin.HandleFunc("/currencies/{id}", handler.UpdateCurrency).Methods("PATCH")
+	admin.HandleFunc("/currencies/{id}", handler.DeleteCurrency).Methods("DELETE")
+	admin.HandleFunc("/ingestion", handler.GetIngestion).Methods("GET")
+	return router
+}
+
+func adminRequest(method, path, body, token string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestAdminAuth(t *testing.T) {
+	router := newTestAdminRouter(newMockAdminRepository())
+
+	testCases := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"No token", "", http.StatusUnauthorized},
+		{"Wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"Token without scheme", "secret", http.StatusUnauthorized},
+		{"Valid token", "Bearer secret", http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := adminRequest("DELETE", "/api/v1/admin/currencies/1", "", "")
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.want {
+				t.Errorf("Expected status %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestAdminAuth_Disabled(t *testing.T) {
+	handler := AdminAuth("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called when admin API is disabled")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, adminRequest("DELETE", "/api/v1/admin/currencies/1", "", ""))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestAdminHandler_CreateCurrency(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"Valid", `{"name": "solana", "display_name": "Solana", "symbol": "sol"}`, http.StatusCreated},
+		{"Unknown to provider", `{"name": "notacoin", "symbol": "NAC"}`, http.StatusUnprocessableEntity},
+		{"Duplicate", `{"name": "bitcoin", "symbol": "BTC"}`, http.StatusConflict},
+		{"Invalid name", `{"name": "Not A Coin!", "symbol": "NAC"}`, http.StatusBadRequest},
+		{"Missing symbol", `{"name": "solana"}`, http.StatusBadRequest},
+		{"Max age too small", `{"name": "solana", "symbol": "SOL", "max_age_seconds": 10}`, http.StatusBadRequest},
+		// 18446747674 с в наносекундах переполняет time.Duration и даёт около часа
+		{"Max age overflow", `{"name": "solana", "symbol": "SOL", "max_age_seconds": 18446747674}`, http.StatusBadRequest},
+		{"Invalid JSON", `{`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newTestAdminRouter(newMockAdminRepository())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, adminRequest("POST", "/api/v1/admin/currencies", tc.body, "secret"))
+
+			if w.Code != tc.want {
+				t.Errorf("Expected status %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminHandler_CreateCurrency_Response(t *testing.T) {
+	repo := newMockAdminRepository()
+	router := newTestAdminRouter(repo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/api/v1/admin/currencies",
+		`{"name": "dogecoin", "display_name": "Dogecoin", "symbol": "doge", "is_tracked": false}`, "secret"))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	var response struct {
+		Success bool             `json:"success"`
+		Data    CurrencyResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Data.ID != 2 || response.Data.Symbol != "DOGE" || response.Data.IsTracked {
+		t.Errorf("Unexpected currency in response: %+v", response.Data)
+	}
+}
+
+func TestAdminHandler_UpdateCurrency(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"Rename", "/api/v1/admin/currencies/1", `{"display_name": "Bitcoin Core"}`, http.StatusOK},
+		{"Disable tracking", "/api/v1/admin/currencies/1", `{"is_tracked": false}`, http.StatusOK},
+		{"Re-symbol to unknown coin", "/api/v1/admin/currencies/1", `{"name": "notacoin"}`, http.StatusUnprocessableEntity},
+		{"Invalid symbol", "/api/v1/admin/currencies/1", `{"symbol": ""}`, http.StatusBadRequest},
+		{"Default max age", "/api/v1/admin/currencies/1", `{"max_age_seconds": 0}`, http.StatusOK},
+		{"Max age overflow", "/api/v1/admin/currencies/1", `{"max_age_seconds": 18446747674}`, http.StatusBadRequest},
+		{"Not found", "/api/v1/admin/currencies/42", `{"display_name": "X"}`, http.StatusNotFound},
+		{"Invalid id", "/api/v1/admin/currencies/abc", `{}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newTestAdminRouter(newMockAdminRepository())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, adminRequest("PATCH", tc.path, tc.body, "secret"))
+
+			if w.Code != tc.want {
+				t.Errorf("Expected status %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminHandler_DeleteCurrency(t *testing.T) {
+	repo := newMockAdminRepository()
+	router := newTestAdminRouter(repo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("DELETE", "/api/v1/admin/currencies/1", "", "secret"))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("DELETE", "/api/v1/admin/currencies/1", "", "secret"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for already deleted currency, got %d", w.Code)
+	}
+}
+
+func TestAdminHandler_GetIngestion(t *testing.T) {
+	finished := time.Now().Add(-10 * time.Minute)
+	repo := newMockAdminRepository()
+	repo.runs = []models.FetchRun{
+		{ID: 3, StartedAt: time.Now(), Provider: "coingecko", Status: models.FetchRunRunning},
+		{ID: 2, StartedAt: finished.Add(-time.Second), FinishedAt: &finished, Provider: "coingecko",
+			CoinsRequested: 7, RatesSaved: 6, CoinsSkipped: 1, Status: models.FetchRunPartial},
+		{ID: 1, StartedAt: finished.Add(-time.Hour), Provider: "coingecko", Status: models.FetchRunFailed, Error: "API returned status 429"},
+	}
+	repo.lastSuccess = finished
+	router := newTestAdminRouter(repo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("GET", "/api/v1/admin/ingestion?limit=2", "", "secret"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Data IngestionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Data.Runs) != 2 || response.Data.Runs[1].CoinsSkipped != 1 {
+		t.Errorf("Unexpected runs: %+v", response.Data.Runs)
+	}
+	if response.Data.SecondsSinceSuccess == nil || *response.Data.SecondsSinceSuccess < 600 {
+		t.Errorf("Expected seconds_since_success >= 600, got %v", response.Data.SecondsSinceSuccess)
+	}
+}
+
+func TestAdminHandler_GetIngestion_Empty(t *testing.T) {
+	router := newTestAdminRouter(newMockAdminRepository())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("GET", "/api/v1/admin/ingestion", "", "secret"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"last_success_at":null`) || !strings.Contains(w.Body.String(), `"runs":[]`) {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+
+	for _, limit := range []string{"0", "abc", "501"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, adminRequest("GET", "/api/v1/admin/ingestion?limit="+limit, "", "secret"))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: expected status 400, got %d", limit, w.Code)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"cryptorate-service/internal/models"
+	"net/http"
+	"time"
+)
+
+// candleIntervals допустимые значения ?interval=
+var candleIntervals = map[string]time.Duration{
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// Число свечей в ответе: по умолчанию, если from не задан, и максимум за период
+const (
+	defaultCandleCount = 100
+	maxCandleCount     = 5000
+)
+
+// CandlesResponse свечи OHLC за период. Интервалы без курсов отсутствуют,
+// samples показывает, сколько курсов попало в свечу.
+type CandlesResponse struct {
+	Currency string          `json:"currency"`
+	Quote    string          `json:"quote"`
+	Interval string          `json:"interval"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Candles  []models.Candle `json:"candles"`
+}
+
+// GetCandles возвращает свечи OHLC валюты:
+// /rates/{currency}/candles?interval=1h&from=2024-01-01T00:00:00Z&to=...
+func (h *Handler) GetCandles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	intervalName := r.URL.Query().Get("interval")
+	if intervalName == "" {
+		intervalName = "1h"
+	}
+	interval, ok := candleIntervals[intervalName]
+	if !ok {
+		sendError(w, "Invalid interval: expected 5m, 15m, 1h, 4h or 1d", http.StatusBadRequest)
+		return
+	}
+
+	currencyName, currencyID, ok := h.currencyParam(r)
+	if !ok {
+		sendError(w, "Currency not found", http.StatusNotFound)
+		return
+	}
+
+	quote, ok := quoteParam(r)
+	if !ok {
+		sendError(w, "Invalid quote currency", http.StatusBadRequest)
+		return
+	}
+
+	from, to, msg := periodParams(r, defaultCandleCount*interval)
+	if msg != "" {
+		sendError(w, msg, http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) > maxCandleCount*interval {
+		sendError(w, "Period too long: at most 5000 candles per request", http.StatusBadRequest)
+		return
+	}
+
+	candles, err := h.repo.GetCandles(r.Context(), models.CandleQuery{
+		CurrencyID: currencyID,
+		Quote:      quote,
+		From:       from,
+		To:         to,
+		Interval:   interval,
+	})
+	if err != nil {
+		sendError(w, "Failed to get candles", http.StatusInternalServerError)
+		return
+	}
+	if candles == nil {
+		candles = []models.Candle{}
+	}
+
+	sendJSON(w, Response{
+		Success: true,
+		Data: CandlesResponse{
+			Currency: currencyName,
+			Quote:    quote,
+			Interval: intervalName,
+			From:     from,
+			To:       to,
+			Candles:  candles,
+		},
+		Meta: &Meta{Timestamp: time.Now().Format(time.RFC3339), Version: "1.0"},
+	})
+}
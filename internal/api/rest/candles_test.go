@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"cryptorate-service/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func candlesRequest(t *testing.T, handler *Handler, url string) (int, CandlesResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", url, nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+	w := httptest.NewRecorder()
+
+	handler.GetCandles(w, req)
+
+	var response struct {
+		Data CandlesResponse `json:"data"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+	}
+	return w.Code, response.Data
+}
+
+func TestHandler_GetCandles(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := newTestRepository(t)
+	saveHistory(t, repo, 1, "eur",
+		models.PricePoint{Time: start, Price: models.MustParseDecimal("42000")},
+		models.PricePoint{Time: start.Add(time.Hour), Price: models.MustParseDecimal("42500")},
+		models.PricePoint{Time: start.Add(2 * time.Hour), Price: models.MustParseDecimal("41900")},
+		models.PricePoint{Time: start.Add(3 * time.Hour), Price: models.MustParseDecimal("42300")},
+		models.PricePoint{Time: start.Add(9 * time.Hour), Price: models.MustParseDecimal("42300")},
+	)
+	handler := NewHandler(repo)
+
+	code, response := candlesRequest(t, handler,
+		"/api/v1/rates/bitcoin/candles?interval=4h&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&quote=eur")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	if response.Interval != "4h" || !response.From.Equal(start) || !response.To.Equal(start.Add(24*time.Hour)) ||
+		len(response.Candles) != 2 {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+	first := response.Candles[0]
+	if !first.Time.Equal(start) || first.Open.String() != "42000" || first.High.String() != "42500" ||
+		first.Low.String() != "41900" || first.Close.String() != "42300" || first.Samples != 4 {
+		t.Errorf("Unexpected first candle: %+v", first)
+	}
+	// Интервал без курсов пропущен
+	if !response.Candles[1].Time.Equal(start.Add(8*time.Hour)) || response.Candles[1].Samples != 1 {
+		t.Errorf("Expected sparse candle with 1 sample, got %+v", response.Candles[1])
+	}
+}
+
+func TestHandler_GetCandles_Defaults(t *testing.T) {
+	handler := NewHandler(newTestRepository(t))
+
+	code, response := candlesRequest(t, handler, "/api/v1/rates/bitcoin/candles")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if response.Interval != "1h" {
+		t.Errorf("Expected default interval 1h, got %s", response.Interval)
+	}
+	if got := response.To.Sub(response.From); got != defaultCandleCount*time.Hour {
+		t.Errorf("Expected default period of %d candles, got %v", defaultCandleCount, got)
+	}
+	if response.Candles == nil || len(response.Candles) != 0 {
+		t.Errorf("Expected empty candles array, got %v", response.Candles)
+	}
+}
+
+func TestHandler_GetCandles_InvalidParams(t *testing.T) {
+	handler := NewHandler(newTestRepository(t))
+
+	urls := []string{
+		"/api/v1/rates/bitcoin/candles?interval=2h",
+		"/api/v1/rates/bitcoin/candles?interval=5m&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z",
+		"/api/v1/rates/bitcoin/candles?from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z",
+		"/api/v1/rates/bitcoin/candles?to=tomorrow",
+	}
+	for _, url := range urls {
+		if code, _ := candlesRequest(t, handler, url); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, code)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"context"
 	"cryptorate-service/internal/models"
 	"encoding/json"
 	"net/http"
@@ -23,25 +24,44 @@ type Meta struct {
 }
 
 type RateResponse struct {
-	Currency     string    `json:"currency"`
-	Symbol       string    `json:"symbol"`
-	DisplayName  string    `json:"display_name"`
-	Price        float64   `json:"price"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	DailyMin     float64   `json:"daily_min,omitempty"`
-	DailyMax     float64   `json:"daily_max,omitempty"`
-	HourlyChange float64   `json:"hourly_change,omitempty"`
+	Currency    string `json:"currency"`
+	Symbol      string `json:"symbol"`
+	DisplayName string `json:"display_name"`
+	// Цены передаются строками, чтобы не терять точность: "0.00001234"
+	Price        models.Decimal `json:"price"`
+	Quote        string         `json:"quote"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DailyMin     models.Decimal `json:"daily_min,omitzero"`
+	DailyMax     models.Decimal `json:"daily_max,omitzero"`
+	HourlyChange float64        `json:"hourly_change,omitempty"`
+	// DayChange изменение с начала дня в часовом поясе ?tz=
+	DayChange float64 `json:"day_change,omitempty"`
+	MarketCap float64 `json:"market_cap,omitempty"`
+	Volume24h float64 `json:"volume_24h,omitempty"`
+	// Change24h изменение за 24 часа: от источника, если он его отдаёт,
+	// иначе по сохранённым курсам
+	Change24h float64 `json:"change_24h,omitempty"`
+	// Stale курс старше допустимого для валюты возраста
+	Stale      bool  `json:"stale"`
+	AgeSeconds int64 `json:"age_seconds"`
 }
 
 type StatsResponse struct {
-	Currency     string    `json:"currency"`
-	Symbol       string    `json:"symbol"`
-	DisplayName  string    `json:"display_name"`
-	Current      float64   `json:"current"`
-	DailyMin     float64   `json:"daily_min"`
-	DailyMax     float64   `json:"daily_max"`
-	HourlyChange float64   `json:"hourly_change"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	Currency     string         `json:"currency"`
+	Symbol       string         `json:"symbol"`
+	DisplayName  string         `json:"display_name"`
+	Current      models.Decimal `json:"current"`
+	Quote        string         `json:"quote"`
+	DailyMin     models.Decimal `json:"daily_min"`
+	DailyMax     models.Decimal `json:"daily_max"`
+	HourlyChange float64        `json:"hourly_change"`
+	DayChange    float64        `json:"day_change"`
+	Change24h    float64        `json:"change_24h"`
+	// Timezone часовой пояс, по календарю которого считается день
+	Timezone   string    `json:"timezone"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Stale      bool      `json:"stale"`
+	AgeSeconds int64     `json:"age_seconds"`
 }
 
 type CurrencyResponse struct {
@@ -49,21 +69,28 @@ type CurrencyResponse struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"display_name"`
 	Symbol      string `json:"symbol"`
+	IsTracked   bool   `json:"is_tracked"`
+	// MaxAgeSeconds допустимый возраст курса, после которого он считается устаревшим
+	MaxAgeSeconds int64 `json:"max_age_seconds"`
 }
 
 // RepositoryInterface определяет интерфейс для операций с репозиторием
 type RepositoryInterface interface {
-	GetLatestRates() ([]models.CurrencyRateView, error)
-	GetAllCurrencies() ([]models.Currency, error)
-	GetCurrencyID(name string) (int, error)
-	GetCurrencyIDBySymbol(symbol string) (int, error)
-	GetCurrencyRate(currencyID int) (models.ExchangeRate, error)
-	GetDailyMinMax(currencyID int) (min, max float64, err error)
-	GetHourlyChange(currencyID int) (change float64, err error)
-	GetCurrencySymbolByID(currencyID int) (string, error)
-	GetCurrencyDisplayName(currencyID int) (string, error)
-	Ping() error
-	GetCurrencySymbol(currencyID int) (string, error)
+	GetRateSummaries(ctx context.Context, quote string, loc *time.Location, currencyIDs ...int) ([]models.RateSummary, error)
+	GetAllCurrencies(ctx context.Context) ([]models.Currency, error)
+	GetCurrencyID(ctx context.Context, name string) (int, error)
+	GetCurrencyIDBySymbol(ctx context.Context, symbol string) (int, error)
+	Ping(ctx context.Context) error
+	GetCurrencySymbol(ctx context.Context, currencyID int) (string, error)
+	GetTrackedFreshness(ctx context.Context, quote string) ([]models.CurrencyFreshness, error)
+	GetRateHistory(ctx context.Context, query models.HistoryQuery) ([]models.HistoryPoint, error)
+	GetCandles(ctx context.Context, query models.CandleQuery) ([]models.Candle, error)
+}
+
+// CacheStatsProvider реализует репозиторий с кэшем курсов,
+// его счётчики попадают в ответ /health
+type CacheStatsProvider interface {
+	CacheStats() models.CacheStats
 }
 
 type Handler struct {
@@ -78,39 +105,32 @@ func NewHandler(repo RepositoryInterface) *Handler {
 func (h *Handler) GetRates(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	rates, err := h.repo.GetLatestRates()
+	quote, ok := quoteParam(r)
+	if !ok {
+		sendError(w, "Invalid quote currency", http.StatusBadRequest)
+		return
+	}
+
+	loc, ok := timezoneParam(r)
+	if !ok {
+		sendError(w, "Invalid timezone: expected IANA name like Europe/Moscow", http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := h.repo.GetRateSummaries(r.Context(), quote, loc)
 	if err != nil {
 		sendError(w, "Failed to get rates", http.StatusInternalServerError)
 		return
 	}
 
-	if len(rates) == 0 {
+	if len(summaries) == 0 {
 		sendError(w, "No rates found", http.StatusNotFound)
 		return
 	}
 
-	response := make([]RateResponse, len(rates))
-	for i, rate := range rates {
-		currencyID, err := h.repo.GetCurrencyID(rate.NameCurrency)
-		if err != nil {
-			continue
-		}
-
-		symbol, _ := h.repo.GetCurrencySymbolByID(currencyID)
-		displayName, _ := h.repo.GetCurrencyDisplayName(currencyID)
-		min, max, _ := h.repo.GetDailyMinMax(currencyID)
-		change, _ := h.repo.GetHourlyChange(currencyID)
-
-		response[i] = RateResponse{
-			Currency:     rate.NameCurrency,
-			Symbol:       symbol,
-			DisplayName:  displayName,
-			Price:        rate.Price,
-			UpdatedAt:    rate.RecordedAt,
-			DailyMin:     min,
-			DailyMax:     max,
-			HourlyChange: change,
-		}
+	response := make([]RateResponse, len(summaries))
+	for i, summary := range summaries {
+		response[i] = rateResponse(summary)
 	}
 
 	sendJSON(w, Response{
@@ -128,9 +148,9 @@ func (h *Handler) GetRate(w http.ResponseWriter, r *http.Request) {
 	currencyName := strings.ToLower(vars["currency"])
 
 	// Пробуем найти по символу или имени
-	currencyID, err := h.repo.GetCurrencyIDBySymbol(currencyName)
+	currencyID, err := h.repo.GetCurrencyIDBySymbol(r.Context(), currencyName)
 	if err != nil {
-		currencyID, err = h.repo.GetCurrencyID(currencyName)
+		currencyID, err = h.repo.GetCurrencyID(r.Context(), currencyName)
 	}
 
 	if err != nil {
@@ -138,28 +158,27 @@ func (h *Handler) GetRate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rate, err := h.repo.GetCurrencyRate(currencyID)
-	if err != nil {
-		sendError(w, "Rate not found", http.StatusNotFound)
+	quote, ok := quoteParam(r)
+	if !ok {
+		sendError(w, "Invalid quote currency", http.StatusBadRequest)
 		return
 	}
 
-	symbol, _ := h.repo.GetCurrencySymbolByID(currencyID)
-	displayName, _ := h.repo.GetCurrencyDisplayName(currencyID)
-	min, max, _ := h.repo.GetDailyMinMax(currencyID)
-	change, _ := h.repo.GetHourlyChange(currencyID)
+	loc, ok := timezoneParam(r)
+	if !ok {
+		sendError(w, "Invalid timezone: expected IANA name like Europe/Moscow", http.StatusBadRequest)
+		return
+	}
 
-	response := RateResponse{
-		Currency:     currencyName,
-		Symbol:       symbol,
-		DisplayName:  displayName,
-		Price:        rate.Price,
-		UpdatedAt:    rate.RecordedAt,
-		DailyMin:     min,
-		DailyMax:     max,
-		HourlyChange: change,
+	summaries, err := h.repo.GetRateSummaries(r.Context(), quote, loc, currencyID)
+	if err != nil || len(summaries) == 0 {
+		sendError(w, "Rate not found", http.StatusNotFound)
+		return
 	}
 
+	response := rateResponse(summaries[0])
+	response.Currency = currencyName
+
 	sendJSON(w, Response{
 		Success: true,
 		Data:    response,
@@ -174,9 +193,9 @@ func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	currencyName := strings.ToLower(vars["currency"])
 
-	currencyID, err := h.repo.GetCurrencyIDBySymbol(currencyName)
+	currencyID, err := h.repo.GetCurrencyIDBySymbol(r.Context(), currencyName)
 	if err != nil {
-		currencyID, err = h.repo.GetCurrencyID(currencyName)
+		currencyID, err = h.repo.GetCurrencyID(r.Context(), currencyName)
 	}
 
 	if err != nil {
@@ -184,27 +203,40 @@ func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rate, err := h.repo.GetCurrencyRate(currencyID)
-	if err != nil {
-		sendError(w, "Rate not found", http.StatusNotFound)
+	quote, ok := quoteParam(r)
+	if !ok {
+		sendError(w, "Invalid quote currency", http.StatusBadRequest)
+		return
+	}
+
+	loc, ok := timezoneParam(r)
+	if !ok {
+		sendError(w, "Invalid timezone: expected IANA name like Europe/Moscow", http.StatusBadRequest)
 		return
 	}
 
-	symbol, _ := h.repo.GetCurrencySymbolByID(currencyID)
-	displayName, _ := h.repo.GetCurrencyDisplayName(currencyID)
-	min, max, _ := h.repo.GetDailyMinMax(currencyID)
-	change, _ := h.repo.GetHourlyChange(currencyID)
+	summaries, err := h.repo.GetRateSummaries(r.Context(), quote, loc, currencyID)
+	if err != nil || len(summaries) == 0 {
+		sendError(w, "Rate not found", http.StatusNotFound)
+		return
+	}
+	summary := summaries[0]
 
 	response := StatsResponse{
 		Currency:     currencyName,
-		Symbol:       symbol,
-		DisplayName:  displayName,
-		Current:      rate.Price,
-		DailyMin:     min,
-		DailyMax:     max,
-		HourlyChange: change,
-		UpdatedAt:    rate.RecordedAt,
+		Symbol:       summary.Symbol,
+		DisplayName:  summary.DisplayName,
+		Current:      summary.Price,
+		Quote:        quote,
+		DailyMin:     summary.DailyMin,
+		DailyMax:     summary.DailyMax,
+		HourlyChange: summary.HourlyChange,
+		DayChange:    summary.DayChange,
+		Change24h:    summary.Change24h,
+		Timezone:     loc.String(),
+		UpdatedAt:    summary.RecordedAt,
 	}
+	response.AgeSeconds, response.Stale = freshness(summary.RecordedAt, summary.MaxAge)
 
 	sendJSON(w, Response{
 		Success: true,
@@ -217,7 +249,7 @@ func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	currencies, err := h.repo.GetAllCurrencies()
+	currencies, err := h.repo.GetAllCurrencies(r.Context())
 	if err != nil {
 		sendError(w, "Failed to get currencies", http.StatusInternalServerError)
 		return
@@ -225,12 +257,7 @@ func (h *Handler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
 
 	response := make([]CurrencyResponse, len(currencies))
 	for i, currency := range currencies {
-		response[i] = CurrencyResponse{
-			ID:          currency.ID,
-			Name:        currency.NameCurrency,
-			DisplayName: currency.DisplayName,
-			Symbol:      currency.Symbol,
-		}
+		response[i] = toCurrencyResponse(currency)
 	}
 
 	sendJSON(w, Response{
@@ -245,7 +272,7 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Проверяем соединение с БД
-	dbErr := h.repo.Ping()
+	dbErr := h.repo.Ping(r.Context())
 
 	health := map[string]interface{}{
 		"status":    "healthy",
@@ -260,6 +287,17 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		health["status"] = "unhealthy"
 		health["database"] = "disconnected"
 		health["error"] = dbErr.Error()
+	} else if stale, err := h.staleCurrencies(r.Context()); err != nil {
+		health["status"] = "degraded"
+		health["error"] = err.Error()
+	} else if len(stale) > 0 {
+		// Воркер не обновлял курсы дольше допустимого
+		health["status"] = "degraded"
+		health["stale_currencies"] = stale
+	}
+
+	if cache, ok := h.repo.(CacheStatsProvider); ok {
+		health["cache"] = cache.CacheStats()
 	}
 
 	sendJSON(w, Response{
@@ -270,6 +308,90 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // Вспомогательные методы
+
+// staleCurrencies возвращает отслеживаемые валюты, курс которых устарел
+// или ещё ни разу не загружался
+func (h *Handler) staleCurrencies(ctx context.Context) ([]string, error) {
+	items, err := h.repo.GetTrackedFreshness(ctx, models.DefaultQuote)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := []string{}
+	now := time.Now()
+	for _, item := range items {
+		if item.LastRecordedAt == nil {
+			stale = append(stale, item.NameCurrency)
+			continue
+		}
+		if _, isStale := models.Freshness(*item.LastRecordedAt, item.MaxAge, now); isStale {
+			stale = append(stale, item.NameCurrency)
+		}
+	}
+	return stale, nil
+}
+
+// freshness возвращает возраст курса в секундах и признак устаревания
+func freshness(recordedAt time.Time, maxAge time.Duration) (int64, bool) {
+	age, stale := models.Freshness(recordedAt, maxAge, time.Now())
+	return int64(age.Seconds()), stale
+}
+
+// rateResponse собирает ответ по сводке курса валюты
+func rateResponse(summary models.RateSummary) RateResponse {
+	response := RateResponse{
+		Currency:     summary.NameCurrency,
+		Symbol:       summary.Symbol,
+		DisplayName:  summary.DisplayName,
+		Price:        summary.Price,
+		Quote:        summary.Quote,
+		UpdatedAt:    summary.RecordedAt,
+		DailyMin:     summary.DailyMin,
+		DailyMax:     summary.DailyMax,
+		HourlyChange: summary.HourlyChange,
+		DayChange:    summary.DayChange,
+		Change24h:    summary.Change24h,
+	}
+	setMarket(&response, summary.Market)
+	response.AgeSeconds, response.Stale = freshness(summary.RecordedAt, summary.MaxAge)
+	return response
+}
+
+// setMarket добавляет в ответ рыночные показатели, если источник их отдал
+func setMarket(response *RateResponse, market *models.MarketData) {
+	if market == nil {
+		return
+	}
+	response.MarketCap = market.MarketCap
+	response.Volume24h = market.Volume24h
+	response.Change24h = market.Change24h
+}
+
+// quoteParam возвращает валюту котировки из параметра ?quote=eur.
+// Без параметра используется котировка по умолчанию (usd).
+func quoteParam(r *http.Request) (string, bool) {
+	quote := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("quote")))
+	if quote == "" {
+		return models.DefaultQuote, true
+	}
+	if len(quote) > 10 {
+		return "", false
+	}
+	for _, ch := range quote {
+		if ch < 'a' || ch > 'z' {
+			return "", false
+		}
+	}
+	return quote, true
+}
+
+// timezoneParam возвращает часовой пояс из параметра ?tz=Europe/Moscow,
+// по календарю которого считается дневная статистика. Без параметра - UTC.
+func timezoneParam(r *http.Request) (*time.Location, bool) {
+	loc, err := models.LoadTimezone(strings.TrimSpace(r.URL.Query().Get("tz")))
+	return loc, err == nil
+}
+
 func sendJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
@@ -1,587 +1,840 @@
 package rest
 
 import (
-    "cryptorate-service/internal/models"
-    "encoding/json"
-    "fmt"
-    "net/http"
-    "net/http/httptest"
-    "testing"
-    "time"
-
-    "github.com/gorilla/mux"
+	"context"
+	"cryptorate-service/internal/models"
+	"cryptorate-service/internal/repository"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
-// MockRepository для тестирования
-type MockRepository struct {
-    rates      []models.CurrencyRateView
-    currencies []models.Currency
-    err        error
+// newTestRepository хранилище в памяти с отслеживаемыми валютами
+// bitcoin (ID 1) и ethereum (ID 2) без курсов
+func newTestRepository(tb testing.TB) *repository.MemoryRepository {
+	tb.Helper()
+	repo := repository.NewMemoryRepository()
+	for _, currency := range []models.Currency{
+		{NameCurrency: "bitcoin", DisplayName: "Bitcoin", Symbol: "BTC", IsTracked: true},
+		{NameCurrency: "ethereum", DisplayName: "Ethereum", Symbol: "ETH", IsTracked: true},
+	} {
+		if _, err := repo.CreateCurrency(context.Background(), currency); err != nil {
+			tb.Fatalf("CreateCurrency failed: %v", err)
+		}
+	}
+	return repo
 }
 
-func (m *MockRepository) GetLatestRates() ([]models.CurrencyRateView, error) {
-    return m.rates, m.err
+// saveRates сохраняет курсы одного цикла с текущим временем
+func saveRates(tb testing.TB, repo *repository.MemoryRepository, rates ...models.ExchangeRate) {
+	tb.Helper()
+	if _, err := repo.SaveRates(context.Background(), rates); err != nil {
+		tb.Fatalf("SaveRates failed: %v", err)
+	}
 }
 
-func (m *MockRepository) GetAllCurrencies() ([]models.Currency, error) {
-    return m.currencies, m.err
+// saveHistory сохраняет курсы валюты на заданное время
+func saveHistory(tb testing.TB, repo *repository.MemoryRepository, currencyID int, quote string, points ...models.PricePoint) {
+	tb.Helper()
+	if _, err := repo.SaveHistoricalRates(context.Background(), currencyID, quote, "test", points); err != nil {
+		tb.Fatalf("SaveHistoricalRates failed: %v", err)
+	}
 }
 
-func (m *MockRepository) GetCurrencyID(name string) (int, error) {
-    if name == "bitcoin" || name == "btc" {
-        return 1, m.err
-    } else if name == "ethereum" || name == "eth" {
-        return 2, m.err
-    }
-    return 0, fmt.Errorf("currency not found: %s", name)
+// failingRepository хранилище, из которого не читаются курсы и валюты
+type failingRepository struct {
+	*repository.MemoryRepository
+	err error
 }
 
-func (m *MockRepository) GetCurrencyIDBySymbol(symbol string) (int, error) {
-    if symbol == "BTC" || symbol == "btc" {
-        return 1, m.err
-    } else if symbol == "ETH" || symbol == "eth" {
-        return 2, m.err
-    }
-    return 0, fmt.Errorf("symbol not found: %s", symbol)
+func (f failingRepository) GetRateSummaries(ctx context.Context, quote string, loc *time.Location, currencyIDs ...int) ([]models.RateSummary, error) {
+	return nil, f.err
 }
 
-func (m *MockRepository) GetCurrencyRate(currencyID int) (models.ExchangeRate, error) {
-    return models.ExchangeRate{
-        ID:         1,
-        CurrencyID: currencyID,
-        Price:      45000.50,
-        RecordedAt: time.Now(),
-    }, m.err
-}
-
-func (m *MockRepository) GetDailyMinMax(currencyID int) (min, max float64, err error) {
-    return 44500.00, 45500.75, m.err
-}
-
-func (m *MockRepository) GetHourlyChange(currencyID int) (change float64, err error) {
-    return 1.25, m.err
-}
-
-func (m *MockRepository) GetCurrencySymbolByID(currencyID int) (string, error) {
-    if currencyID == 1 {
-        return "BTC", m.err
-    } else if currencyID == 2 {
-        return "ETH", m.err
-    }
-    return "", fmt.Errorf("currency ID not found: %d", currencyID)
-}
-
-func (m *MockRepository) GetCurrencyDisplayName(currencyID int) (string, error) {
-    if currencyID == 1 {
-        return "Bitcoin", m.err
-    } else if currencyID == 2 {
-        return "Ethereum", m.err
-    }
-    return "", fmt.Errorf("currency ID not found: %d", currencyID)
-}
-
-func (m *MockRepository) Ping() error {
-    return m.err
-}
-
-func (m *MockRepository) DB() interface{} {
-    return nil
-}
-
-func (m *MockRepository) GetCurrencySymbol(currencyID int) (string, error) {
-    if currencyID == 1 {
-        return "BTC", m.err
-    } else if currencyID == 2 {
-        return "ETH", m.err
-    }
-    return "", fmt.Errorf("currency ID not found: %d", currencyID)
+func (f failingRepository) GetAllCurrencies(ctx context.Context) ([]models.Currency, error) {
+	return nil, f.err
 }
 
 func TestHandler_GetRates(t *testing.T) {
-    // Подготовка мок данных
-    mockRates := []models.CurrencyRateView{
-        {
-            NameCurrency: "bitcoin",
-            Price:        45000.50,
-            RecordedAt:   time.Now(),
-        },
-    }
-
-    repo := &MockRepository{rates: mockRates}
-    handler := NewHandler(repo)
-
-    // Создание запроса
-    req := httptest.NewRequest("GET", "/api/v1/rates", nil)
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetRates(w, req)
-
-    // Проверка статуса
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if !response.Success {
-        t.Error("Expected success to be true")
-    }
-
-    // Проверка данных
-    ratesData, ok := response.Data.([]interface{})
-    if !ok || len(ratesData) == 0 {
-        t.Error("Expected rates data in response")
-    }
+	// Подготовка данных
+	repo := newTestRepository(t)
+	saveRates(t, repo, models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal("45000.50")})
+	handler := NewHandler(repo)
+
+	// Создание запроса
+	req := httptest.NewRequest("GET", "/api/v1/rates", nil)
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetRates(w, req)
+
+	// Проверка статуса
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Success {
+		t.Error("Expected success to be true")
+	}
+
+	// Проверка данных
+	ratesData, ok := response.Data.([]interface{})
+	if !ok || len(ratesData) == 0 {
+		t.Error("Expected rates data in response")
+	}
 }
 
 func TestHandler_GetCurrencies(t *testing.T) {
-    mockCurrencies := []models.Currency{
-        {ID: 1, NameCurrency: "bitcoin", DisplayName: "Bitcoin", Symbol: "BTC"},
-        {ID: 2, NameCurrency: "ethereum", DisplayName: "Ethereum", Symbol: "ETH"},
-    }
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
 
-    repo := &MockRepository{currencies: mockCurrencies}
-    handler := NewHandler(repo)
+	req := httptest.NewRequest("GET", "/api/v1/currencies", nil)
+	w := httptest.NewRecorder()
 
-    req := httptest.NewRequest("GET", "/api/v1/currencies", nil)
-    w := httptest.NewRecorder()
+	handler.GetCurrencies(w, req)
 
-    handler.GetCurrencies(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
 
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
 
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
+	if !response.Success {
+		t.Error("Expected success to be true")
+	}
 
-    if !response.Success {
-        t.Error("Expected success to be true")
-    }
+	currencies, ok := response.Data.([]interface{})
+	if !ok || len(currencies) != 2 {
+		t.Errorf("Expected 2 currencies, got %v", response.Data)
+	}
 }
 
 func TestHandler_HealthCheck(t *testing.T) {
-    repo := &MockRepository{}
-    handler := NewHandler(repo)
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
 
-    req := httptest.NewRequest("GET", "/api/v1/health", nil)
-    w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/health", nil)
+	w := httptest.NewRecorder()
 
-    handler.HealthCheck(w, req)
+	handler.HealthCheck(w, req)
 
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
 
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
 
-    if !response.Success {
-        t.Error("Expected success to be true")
-    }
+	if !response.Success {
+		t.Error("Expected success to be true")
+	}
 
-    // Проверка health данных
-    healthData, ok := response.Data.(map[string]interface{})
-    if !ok {
-        t.Error("Expected health data in response")
-    }
+	// Проверка health данных
+	healthData, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Error("Expected health data in response")
+	}
 
-    if status, ok := healthData["status"].(string); !ok || status == "" {
-        t.Error("Expected status in health response")
-    }
+	if status, ok := healthData["status"].(string); !ok || status == "" {
+		t.Error("Expected status in health response")
+	}
 }
 
-func TestHandler_GetRate(t *testing.T) {
-    // Подготовка мок данных
-    repo := &MockRepository{}
-    handler := NewHandler(repo)
-
-    // Создание запроса с параметром
-    req := httptest.NewRequest("GET", "/api/v1/rate/bitcoin", nil)
-    req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetRate(w, req)
-
-    // Проверка статуса
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
+// cachedRepository хранилище с кэшем курсов
+type cachedRepository struct {
+	*repository.MemoryRepository
+	stats models.CacheStats
+}
 
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
+func (c cachedRepository) CacheStats() models.CacheStats {
+	return c.stats
+}
 
-    if !response.Success {
-        t.Error("Expected success to be true")
-    }
+func TestHandler_HealthCheck_CacheStats(t *testing.T) {
+	stats := models.CacheStats{Hits: 3, Misses: 1, Invalidations: 2, HitRatio: 0.75}
+
+	for _, tc := range []struct {
+		name string
+		repo RepositoryInterface
+		want bool
+	}{
+		{"Without cache", newTestRepository(t), false},
+		{"With cache", cachedRepository{MemoryRepository: newTestRepository(t), stats: stats}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewHandler(tc.repo).HealthCheck(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+
+			var response struct {
+				Data struct {
+					Cache *models.CacheStats `json:"cache"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+
+			if !tc.want {
+				if response.Data.Cache != nil {
+					t.Errorf("Expected no cache stats, got %+v", response.Data.Cache)
+				}
+				return
+			}
+			if response.Data.Cache == nil || *response.Data.Cache != stats {
+				t.Errorf("Expected cache stats %+v, got %+v", stats, response.Data.Cache)
+			}
+		})
+	}
+}
 
-    // Проверка данных
-    _, ok := response.Data.(map[string]interface{})
-    if !ok {
-        t.Error("Expected rate data in response")
-    }
+func TestHandler_GetRate(t *testing.T) {
+	// Подготовка данных: курс сутки назад и три курса за сегодня
+	repo := newTestRepository(t)
+	saveHistory(t, repo, 1, "usd", models.PricePoint{Time: time.Now().Add(-25 * time.Hour), Price: models.MustParseDecimal("36000.40")})
+	for _, price := range []string{"44500.00", "45500.75", "45000.50"} {
+		saveRates(t, repo, models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal(price)})
+	}
+	handler := NewHandler(repo)
+
+	// Создание запроса с параметром
+	req := httptest.NewRequest("GET", "/api/v1/rate/bitcoin", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetRate(w, req)
+
+	// Проверка статуса
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Success {
+		t.Error("Expected success to be true")
+	}
+
+	// Проверка данных: цены передаются строками без потери точности
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected rate data in response")
+	}
+	if data["price"] != "45000.5" || data["daily_min"] != "44500" || data["daily_max"] != "45500.75" {
+		t.Errorf("Unexpected prices: %v, %v, %v", data["price"], data["daily_min"], data["daily_max"])
+	}
+	// Источник не отдал изменение за 24 часа - оно считается по сохранённым курсам
+	if change, _ := data["change_24h"].(float64); math.Abs(change-25) > 1e-9 {
+		t.Errorf("Expected change_24h from stored rates, got %v", data["change_24h"])
+	}
 }
 
 func TestHandler_GetRate_Error(t *testing.T) {
-    // Тестирование ошибки при поиске валюты
-    repo := &MockRepository{err: fmt.Errorf("currency not found")}
-    handler := NewHandler(repo)
-
-    // Создание запроса с параметром
-    req := httptest.NewRequest("GET", "/api/v1/rate/nonexistent", nil)
-    req = mux.SetURLVars(req, map[string]string{"currency": "nonexistent"})
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetRate(w, req)
-
-    // Проверка статуса - должен быть 404
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
-
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+	// Тестирование валюты, курсы которой ещё не загружались
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
+
+	// Создание запроса с параметром
+	req := httptest.NewRequest("GET", "/api/v1/rate/ethereum", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "ethereum"})
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetRate(w, req)
+
+	// Проверка статуса - должен быть 404
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
 }
 
 func TestHandler_GetRate_NotFound(t *testing.T) {
-    // Тестирование случая, когда валюта не найдена
-    repo := &MockRepository{}
-    handler := NewHandler(repo)
-
-    // Создание запроса с несуществующей валютой
-    req := httptest.NewRequest("GET", "/api/v1/rate/nonexistent", nil)
-    req = mux.SetURLVars(req, map[string]string{"currency": "nonexistent"})
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetRate(w, req)
-
-    // Проверка статуса - должен быть 404
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
-
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+	// Тестирование случая, когда валюта не найдена
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
+
+	// Создание запроса с несуществующей валютой
+	req := httptest.NewRequest("GET", "/api/v1/rate/nonexistent", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "nonexistent"})
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetRate(w, req)
+
+	// Проверка статуса - должен быть 404
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
 }
 
 func TestHandler_GetStats(t *testing.T) {
-    // Подготовка мок данных
-    repo := &MockRepository{}
-    handler := NewHandler(repo)
-
-    // Создание запроса с параметром
-    req := httptest.NewRequest("GET", "/api/v1/stats/bitcoin", nil)
-    req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetStats(w, req)
-
-    // Проверка статуса
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if !response.Success {
-        t.Error("Expected success to be true")
-    }
-
-    // Проверка данных
-    _, ok := response.Data.(map[string]interface{})
-    if !ok {
-        t.Error("Expected stats data in response")
-    }
+	// Подготовка данных
+	repo := newTestRepository(t)
+	saveRates(t, repo, models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal("45000.50")})
+	handler := NewHandler(repo)
+
+	// Создание запроса с параметром
+	req := httptest.NewRequest("GET", "/api/v1/stats/bitcoin", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetStats(w, req)
+
+	// Проверка статуса
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Success {
+		t.Error("Expected success to be true")
+	}
+
+	// Проверка данных
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected stats data in response")
+	}
+	if data["current"] != "45000.5" || data["symbol"] != "BTC" || data["display_name"] != "Bitcoin" {
+		t.Errorf("Unexpected stats: %v", data)
+	}
 }
 
 func TestHandler_GetStats_Error(t *testing.T) {
-    // Тестирование ошибки при поиске статистики
-    repo := &MockRepository{err: fmt.Errorf("currency not found")}
-    handler := NewHandler(repo)
-
-    // Создание запроса с параметром
-    req := httptest.NewRequest("GET", "/api/v1/stats/nonexistent", nil)
-    req = mux.SetURLVars(req, map[string]string{"currency": "nonexistent"})
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetStats(w, req)
-
-    // Проверка статуса - должен быть 404
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
-
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+	// Тестирование статистики валюты без курсов
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
+
+	// Создание запроса с параметром
+	req := httptest.NewRequest("GET", "/api/v1/stats/eth", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "eth"})
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetStats(w, req)
+
+	// Проверка статуса - должен быть 404
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
 }
 
 func TestHandler_GetStats_NotFound(t *testing.T) {
-    // Тестирование случая, когда статистика не найдена
-    repo := &MockRepository{}
-    handler := NewHandler(repo)
-
-    // Создание запроса с несуществующей валютой
-    req := httptest.NewRequest("GET", "/api/v1/stats/nonexistent", nil)
-    req = mux.SetURLVars(req, map[string]string{"currency": "nonexistent"})
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetStats(w, req)
-
-    // Проверка статуса - должен быть 404
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
-
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+	// Тестирование случая, когда статистика не найдена
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
+
+	// Создание запроса с несуществующей валютой
+	req := httptest.NewRequest("GET", "/api/v1/stats/nonexistent", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "nonexistent"})
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetStats(w, req)
+
+	// Проверка статуса - должен быть 404
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
 }
 
 func TestHandler_GetRates_Empty(t *testing.T) {
-    // Тестирование случая, когда нет курсов валют
-    repo := &MockRepository{rates: []models.CurrencyRateView{}}
-    handler := NewHandler(repo)
-
-    // Создание запроса
-    req := httptest.NewRequest("GET", "/api/v1/rates", nil)
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetRates(w, req)
-
-    // Проверка статуса - должен быть 404
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
-
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+	// Тестирование случая, когда нет курсов валют
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
+
+	// Создание запроса
+	req := httptest.NewRequest("GET", "/api/v1/rates", nil)
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetRates(w, req)
+
+	// Проверка статуса - должен быть 404
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
 }
 
 func TestHandler_GetRates_Error(t *testing.T) {
-    // Тестирование ошибки при получении курсов
-    repo := &MockRepository{err: fmt.Errorf("database error")}
-    handler := NewHandler(repo)
-
-    // Создание запроса
-    req := httptest.NewRequest("GET", "/api/v1/rates", nil)
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetRates(w, req)
-
-    // Проверка статуса - должен быть 500
-    if w.Code != http.StatusInternalServerError {
-        t.Errorf("Expected status 500, got %d", w.Code)
-    }
-
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
-
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+	// Тестирование ошибки при получении курсов
+	repo := failingRepository{MemoryRepository: newTestRepository(t), err: fmt.Errorf("database error")}
+	handler := NewHandler(repo)
+
+	// Создание запроса
+	req := httptest.NewRequest("GET", "/api/v1/rates", nil)
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetRates(w, req)
+
+	// Проверка статуса - должен быть 500
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
 }
 
 func TestHandler_GetCurrencies_Error(t *testing.T) {
-    // Тестирование ошибки при получении списка валют
-    repo := &MockRepository{err: fmt.Errorf("database error")}
-    handler := NewHandler(repo)
-
-    // Создание запроса
-    req := httptest.NewRequest("GET", "/api/v1/currencies", nil)
-    w := httptest.NewRecorder()
-
-    // Выполнение запроса
-    handler.GetCurrencies(w, req)
-
-    // Проверка статуса - должен быть 500
-    if w.Code != http.StatusInternalServerError {
-        t.Errorf("Expected status 500, got %d", w.Code)
-    }
+	// Тестирование ошибки при получении списка валют
+	repo := failingRepository{MemoryRepository: newTestRepository(t), err: fmt.Errorf("database error")}
+	handler := NewHandler(repo)
+
+	// Создание запроса
+	req := httptest.NewRequest("GET", "/api/v1/currencies", nil)
+	w := httptest.NewRecorder()
+
+	// Выполнение запроса
+	handler.GetCurrencies(w, req)
+
+	// Проверка статуса - должен быть 500
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+
+	// Проверка JSON ответа
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Success {
+		t.Error("Expected success to be false")
+	}
+
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
+}
 
-    // Проверка JSON ответа
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
+func TestHandler_HealthCheck_Unhealthy(t *testing.T) {
+	// Тестирование случая, когда сервис нездоров: запрос к базе не выполняется
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/api/v1/health", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.HealthCheck(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Success {
+		t.Error("Expected success to be true")
+	}
+
+	// Проверка health данных
+	healthData, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Error("Expected health data in response")
+	}
+
+	if status, ok := healthData["status"].(string); !ok || status != "unhealthy" {
+		t.Error("Expected status to be 'unhealthy' when database is down")
+	}
+}
 
-    if response.Success {
-        t.Error("Expected success to be false")
-    }
+func TestHandler_GetRate_Quote(t *testing.T) {
+	repo := newTestRepository(t)
+	saveRates(t, repo,
+		models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal("45000.50")},
+		models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal("41000.25"), Quote: "eur"},
+	)
+	handler := NewHandler(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/rates/bitcoin?quote=EUR", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+	w := httptest.NewRecorder()
+
+	handler.GetRate(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	rateData, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected rate data in response")
+	}
+
+	if quote, _ := rateData["quote"].(string); quote != "eur" || rateData["price"] != "41000.25" {
+		t.Errorf("Expected eur rate 41000.25, got %v %v", rateData["price"], rateData["quote"])
+	}
+}
 
-    if response.Error == "" {
-        t.Error("Expected error message in response")
-    }
+func TestHandler_GetRates_MarketData(t *testing.T) {
+	repo := newTestRepository(t)
+	saveRates(t, repo,
+		models.ExchangeRate{
+			CurrencyID: 1,
+			Price:      models.MustParseDecimal("45000.50"),
+			Market:     &models.MarketData{MarketCap: 880000000000, Volume24h: 25000000000, Change24h: -1.5},
+		},
+		models.ExchangeRate{CurrencyID: 2, Price: models.MustParseDecimal("2500.75")},
+	)
+	handler := NewHandler(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/rates", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetRates(w, req)
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 rates, got %d", len(response.Data))
+	}
+
+	btc := response.Data[0]
+	if btc["market_cap"] != 880000000000.0 || btc["volume_24h"] != 25000000000.0 || btc["change_24h"] != -1.5 {
+		t.Errorf("Unexpected bitcoin market data: %v", btc)
+	}
+
+	if _, ok := response.Data[1]["market_cap"]; ok {
+		t.Errorf("Expected no market data for ethereum, got %v", response.Data[1])
+	}
 }
 
-func TestHandler_HealthCheck_Unhealthy(t *testing.T) {
-    // Тестирование случая, когда сервис нездоров
-    repo := &MockRepository{err: fmt.Errorf("database connection failed")}
-    handler := NewHandler(repo)
+func TestHandler_GetRates_Stale(t *testing.T) {
+	repo := newTestRepository(t)
+	maxAge := time.Hour
+	if _, err := repo.UpdateCurrency(context.Background(), 2, models.CurrencyUpdate{MaxAge: &maxAge}); err != nil {
+		t.Fatalf("UpdateCurrency failed: %v", err)
+	}
+	recordedAt := time.Now().Add(-20 * time.Minute)
+	saveHistory(t, repo, 1, "usd", models.PricePoint{Time: recordedAt, Price: models.MustParseDecimal("45000.50")})
+	saveHistory(t, repo, 2, "usd", models.PricePoint{Time: recordedAt, Price: models.MustParseDecimal("2500.75")})
+	handler := NewHandler(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/rates", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetRates(w, req)
+
+	var response struct {
+		Data []RateResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 rates, got %d", len(response.Data))
+	}
+
+	// Для bitcoin действует DefaultMaxAge (15 минут), для ethereum - свой час
+	if !response.Data[0].Stale {
+		t.Error("Expected bitcoin rate to be stale")
+	}
+	if response.Data[1].Stale {
+		t.Error("Expected ethereum rate to be fresh")
+	}
+	if age := response.Data[0].AgeSeconds; age < 1199 || age > 1260 {
+		t.Errorf("Expected age about 1200 seconds, got %d", age)
+	}
+}
 
-    req := httptest.NewRequest("GET", "/api/v1/health", nil)
-    w := httptest.NewRecorder()
+func TestHandler_HealthCheck_Degraded(t *testing.T) {
+	repo := newTestRepository(t)
+	for _, currency := range []models.Currency{
+		{NameCurrency: "dogecoin", DisplayName: "Dogecoin", Symbol: "DOGE", IsTracked: true, MaxAge: 2 * time.Hour},
+		{NameCurrency: "solana", DisplayName: "Solana", Symbol: "SOL", IsTracked: true},
+	} {
+		if _, err := repo.CreateCurrency(context.Background(), currency); err != nil {
+			t.Fatalf("CreateCurrency failed: %v", err)
+		}
+	}
+	old := models.PricePoint{Time: time.Now().Add(-time.Hour), Price: models.MustParseDecimal("1")}
+	saveRates(t, repo, models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal("45000.50")})
+	saveHistory(t, repo, 2, "usd", old)
+	saveHistory(t, repo, 3, "usd", old)
+	handler := NewHandler(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/health", nil)
+	w := httptest.NewRecorder()
+
+	handler.HealthCheck(w, req)
+
+	var response struct {
+		Data struct {
+			Status          string   `json:"status"`
+			StaleCurrencies []string `json:"stale_currencies"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Data.Status != "degraded" {
+		t.Errorf("Expected status 'degraded', got '%s'", response.Data.Status)
+	}
+
+	// Валюта без единого курса тоже считается устаревшей
+	stale := response.Data.StaleCurrencies
+	if len(stale) != 2 || stale[0] != "ethereum" || stale[1] != "solana" {
+		t.Errorf("Expected stale currencies [ethereum solana], got %v", stale)
+	}
+}
 
-    handler.HealthCheck(w, req)
+func TestHandler_GetRates_InvalidQuote(t *testing.T) {
+	repo := newTestRepository(t)
+	handler := NewHandler(repo)
 
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
+	req := httptest.NewRequest("GET", "/api/v1/rates?quote=us$", nil)
+	w := httptest.NewRecorder()
 
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
+	handler.GetRates(w, req)
 
-    if !response.Success {
-        t.Error("Expected success to be true")
-    }
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
 
-    // Проверка health данных
-    healthData, ok := response.Data.(map[string]interface{})
-    if !ok {
-        t.Error("Expected health data in response")
-    }
+func TestHandler_GetStats_Timezone(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+	dayStart, _ := models.CalendarDay(time.Now(), moscow)
+
+	// Курс за минуту до полуночи по Москве относится к прошлому дню,
+	// а курс через минуту после - к текущему, в UTC хотя бы один из них
+	// попадает в другой день
+	repo := newTestRepository(t)
+	saveHistory(t, repo, 1, "usd",
+		models.PricePoint{Time: dayStart.Add(-time.Minute), Price: models.MustParseDecimal("100")},
+		models.PricePoint{Time: dayStart.Add(time.Minute), Price: models.MustParseDecimal("200")},
+	)
+	saveRates(t, repo, models.ExchangeRate{CurrencyID: 1, Price: models.MustParseDecimal("45000.50")})
+	handler := NewHandler(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/rates/bitcoin/stats?tz=Europe/Moscow", nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+	w := httptest.NewRecorder()
+
+	handler.GetStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Data StatsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// Дневная статистика считается по календарю переданного часового пояса
+	if response.Data.Timezone != "Europe/Moscow" || response.Data.DailyMin.String() != "200" ||
+		response.Data.DailyMax.String() != "45000.5" || response.Data.DayChange <= 0 {
+		t.Errorf("Unexpected stats: %+v", response.Data)
+	}
+}
 
-    if status, ok := healthData["status"].(string); !ok || status != "unhealthy" {
-        t.Error("Expected status to be 'unhealthy' when database is down")
-    }
+func TestHandler_InvalidTimezone(t *testing.T) {
+	handler := NewHandler(newTestRepository(t))
+
+	tests := []struct {
+		url    string
+		handle http.HandlerFunc
+	}{
+		{"/api/v1/rates?tz=Mars/Olympus", handler.GetRates},
+		{"/api/v1/rates/bitcoin?tz=Local", handler.GetRate},
+		{"/api/v1/rates/bitcoin/stats?tz=%2B03:00", handler.GetStats},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+		w := httptest.NewRecorder()
+
+		tt.handle(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.url, w.Code)
+		}
+	}
 }
 
 // Тестирование вспомогательных функций
 func TestSendJSON(t *testing.T) {
-    w := httptest.NewRecorder()
-    testData := map[string]string{"test": "data"}
+	w := httptest.NewRecorder()
+	testData := map[string]string{"test": "data"}
 
-    sendJSON(w, testData)
+	sendJSON(w, testData)
 
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-    }
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
 
-    if w.Header().Get("Content-Type") != "application/json" {
-        t.Error("Expected Content-Type: application/json")
-    }
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Error("Expected Content-Type: application/json")
+	}
 }
 
 func TestSendError(t *testing.T) {
-    w := httptest.NewRecorder()
-    message := "Test error message"
-    code := http.StatusBadRequest
+	w := httptest.NewRecorder()
+	message := "Test error message"
+	code := http.StatusBadRequest
 
-    sendError(w, message, code)
+	sendError(w, message, code)
 
-    if w.Code != code {
-        t.Errorf("Expected status %d, got %d", code, w.Code)
-    }
+	if w.Code != code {
+		t.Errorf("Expected status %d, got %d", code, w.Code)
+	}
 
-    var response Response
-    if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-        t.Fatalf("Failed to parse error response: %v", err)
-    }
+	var response Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
 
-    if response.Success {
-        t.Error("Expected success to be false for error response")
-    }
+	if response.Success {
+		t.Error("Expected success to be false for error response")
+	}
 
-    if response.Error != message {
-        t.Errorf("Expected error message '%s', got '%s'", message, response.Error)
-    }
+	if response.Error != message {
+		t.Errorf("Expected error message '%s', got '%s'", message, response.Error)
+	}
 }
 
 // Бенчмарк тест
 func BenchmarkHealthCheck(b *testing.B) {
-    repo := &MockRepository{}
-    handler := NewHandler(repo)
+	repo := newTestRepository(b)
+	handler := NewHandler(repo)
 
-    req := httptest.NewRequest("GET", "/api/v1/health", nil)
+	req := httptest.NewRequest("GET", "/api/v1/health", nil)
 
-    for i := 0; i < b.N; i++ {
-        w := httptest.NewRecorder()
-        handler.HealthCheck(w, req)
-    }
-}
\ No newline at end of file
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		handler.HealthCheck(w, req)
+	}
+}
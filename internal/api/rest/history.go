@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"cryptorate-service/internal/models"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Размер страницы /rates/{currency}/history
+const (
+	defaultHistoryLimit = 500
+	maxHistoryLimit     = 5000
+)
+
+// defaultHistoryPeriod период истории, если from не задан
+const defaultHistoryPeriod = 24 * time.Hour
+
+// HistoryResponse страница истории курса. NextCursor передаётся в ?cursor=
+// для получения следующей страницы, пустой - страница последняя.
+type HistoryResponse struct {
+	Currency   string                `json:"currency"`
+	Quote      string                `json:"quote"`
+	From       time.Time             `json:"from"`
+	To         time.Time             `json:"to"`
+	Points     []models.HistoryPoint `json:"points"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// GetHistory возвращает курсы валюты за период:
+// /rates/{currency}/history?from=2024-01-01T00:00:00Z&to=...&limit=500&cursor=...
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	currencyName, currencyID, ok := h.currencyParam(r)
+	if !ok {
+		sendError(w, "Currency not found", http.StatusNotFound)
+		return
+	}
+
+	quote, ok := quoteParam(r)
+	if !ok {
+		sendError(w, "Invalid quote currency", http.StatusBadRequest)
+		return
+	}
+
+	from, to, msg := periodParams(r, defaultHistoryPeriod)
+	if msg != "" {
+		sendError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			sendError(w, "Invalid limit: expected 1-5000", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	query := models.HistoryQuery{CurrencyID: currencyID, Quote: quote, From: from, To: to, Limit: limit + 1}
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			sendError(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query.After = &cursor
+	}
+
+	points, err := h.repo.GetRateHistory(r.Context(), query)
+	if err != nil {
+		sendError(w, "Failed to get history", http.StatusInternalServerError)
+		return
+	}
+
+	response := HistoryResponse{Currency: currencyName, Quote: quote, From: from, To: to, Points: points}
+	// Лишняя точка означает, что есть следующая страница
+	if len(points) > limit {
+		response.Points = points[:limit]
+		last := response.Points[limit-1]
+		response.NextCursor = encodeCursor(models.HistoryCursor{Time: last.Time, ID: last.ID})
+	}
+	if response.Points == nil {
+		response.Points = []models.HistoryPoint{}
+	}
+
+	sendJSON(w, Response{
+		Success: true,
+		Data:    response,
+		Meta:    &Meta{Timestamp: time.Now().Format(time.RFC3339), Version: "1.0"},
+	})
+}
+
+// currencyParam ищет валюту из пути по символу или имени
+func (h *Handler) currencyParam(r *http.Request) (string, int, bool) {
+	currencyName := strings.ToLower(mux.Vars(r)["currency"])
+
+	currencyID, err := h.repo.GetCurrencyIDBySymbol(r.Context(), currencyName)
+	if err != nil {
+		currencyID, err = h.repo.GetCurrencyID(r.Context(), currencyName)
+	}
+	return currencyName, currencyID, err == nil
+}
+
+// periodParams разбирает ?from= и ?to= в формате RFC3339 или unix-секундах.
+// Без to период заканчивается сейчас, без from длится defaultPeriod.
+// Возвращает текст ошибки или пустую строку.
+func periodParams(r *http.Request, defaultPeriod time.Duration) (from, to time.Time, msg string) {
+	to = time.Now().UTC()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			return from, to, "Invalid to: expected RFC3339 or unix seconds"
+		}
+		to = parsed
+	}
+
+	from = to.Add(-defaultPeriod)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			return from, to, "Invalid from: expected RFC3339 or unix seconds"
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return from, to, "Invalid period: from must be before to"
+	}
+	return from, to, ""
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
+
+// encodeCursor кодирует позицию в истории в непрозрачную строку
+func encodeCursor(cursor models.HistoryCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.Time.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (models.HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return models.HistoryCursor{}, err
+	}
+
+	nanosSpec, idSpec, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return models.HistoryCursor{}, fmt.Errorf("invalid cursor %q", value)
+	}
+	nanos, err := strconv.ParseInt(nanosSpec, 10, 64)
+	if err != nil {
+		return models.HistoryCursor{}, err
+	}
+	id, err := strconv.Atoi(idSpec)
+	if err != nil {
+		return models.HistoryCursor{}, err
+	}
+
+	return models.HistoryCursor{Time: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
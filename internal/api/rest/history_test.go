@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"cryptorate-service/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func historyRequest(t *testing.T, handler *Handler, url string) (int, HistoryResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", url, nil)
+	req = mux.SetURLVars(req, map[string]string{"currency": "bitcoin"})
+	w := httptest.NewRecorder()
+
+	handler.GetHistory(w, req)
+
+	var response struct {
+		Data HistoryResponse `json:"data"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+	}
+	return w.Code, response.Data
+}
+
+func TestHandler_GetHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := newTestRepository(t)
+	saveHistory(t, repo, 1, "eur",
+		models.PricePoint{Time: start.Add(-time.Hour), Price: models.MustParseDecimal("41900")},
+		models.PricePoint{Time: start, Price: models.MustParseDecimal("42000")},
+		models.PricePoint{Time: start.Add(5 * time.Minute), Price: models.MustParseDecimal("42100")},
+		models.PricePoint{Time: start.Add(10 * time.Minute), Price: models.MustParseDecimal("42200")},
+	)
+	// Курс в другой котировке в историю не попадает
+	saveHistory(t, repo, 1, "usd", models.PricePoint{Time: start.Add(time.Minute), Price: models.MustParseDecimal("45000")})
+	handler := NewHandler(repo)
+
+	code, page := historyRequest(t, handler,
+		"/api/v1/rates/bitcoin/history?from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&limit=2&quote=eur")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	if page.Quote != "eur" || !page.From.Equal(start) || !page.To.Equal(start.Add(24*time.Hour)) {
+		t.Errorf("Unexpected page: %s %v - %v", page.Quote, page.From, page.To)
+	}
+	if len(page.Points) != 2 || page.Points[0].Price.String() != "42000" || page.Points[1].Price.String() != "42100" {
+		t.Fatalf("Unexpected points: %+v", page.Points)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("Expected next cursor")
+	}
+
+	// Курсор следующей страницы указывает на последнюю отданную точку,
+	// третий сохранённый курс
+	cursor, err := decodeCursor(page.NextCursor)
+	if err != nil || cursor.ID != 3 || !cursor.Time.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("Unexpected cursor: %+v, %v", cursor, err)
+	}
+	code, page = historyRequest(t, handler, "/api/v1/rates/bitcoin/history?from=1704067200&quote=eur&limit=2&cursor="+page.NextCursor)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(page.Points) != 1 || page.Points[0].Price.String() != "42200" || page.NextCursor != "" {
+		t.Errorf("Expected last page with 1 point, got %+v", page)
+	}
+}
+
+func TestHandler_GetHistory_Empty(t *testing.T) {
+	handler := NewHandler(newTestRepository(t))
+
+	code, page := historyRequest(t, handler, "/api/v1/rates/bitcoin/history")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if page.Points == nil || len(page.Points) != 0 {
+		t.Errorf("Expected empty points array, got %v", page.Points)
+	}
+	// По умолчанию - последние сутки
+	if got := page.To.Sub(page.From); got != defaultHistoryPeriod {
+		t.Errorf("Expected default period %v, got %v", defaultHistoryPeriod, got)
+	}
+}
+
+func TestHandler_GetHistory_InvalidParams(t *testing.T) {
+	handler := NewHandler(newTestRepository(t))
+
+	urls := []string{
+		"/api/v1/rates/bitcoin/history?from=yesterday",
+		"/api/v1/rates/bitcoin/history?from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z",
+		"/api/v1/rates/bitcoin/history?limit=0",
+		"/api/v1/rates/bitcoin/history?limit=10000",
+		"/api/v1/rates/bitcoin/history?cursor=not-a-cursor",
+		"/api/v1/rates/bitcoin/history?quote=us$",
+	}
+	for _, url := range urls {
+		if code, _ := historyRequest(t, handler, url); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, code)
+		}
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := models.HistoryCursor{Time: time.Date(2024, 1, 1, 12, 30, 0, 123456000, time.UTC), ID: 42}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	if err != nil {
+		t.Fatalf("decodeCursor failed: %v", err)
+	}
+	if !decoded.Time.Equal(cursor.Time) || decoded.ID != cursor.ID {
+		t.Errorf("Expected %+v, got %+v", cursor, decoded)
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy настройки повторных запросов к источнику.
+// Нулевое значение означает одну попытку без повторов.
+type RetryPolicy struct {
+	// MaxAttempts общее число попыток, включая первую
+	MaxAttempts int
+	// BaseDelay задержка перед первым повтором, дальше удваивается
+	BaseDelay time.Duration
+	// MaxDelay максимальная задержка между попытками
+	MaxDelay time.Duration
+	// Budget суммарное время ожидания между попытками. Если следующая
+	// задержка не укладывается в бюджет, возвращается последняя ошибка.
+	Budget time.Duration
+}
+
+// DefaultRetryPolicy повторы для публичного API CoinGecko
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Budget:      time.Minute,
+}
+
+// retryable повторяются только ограничение частоты и недоступность источника
+func retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUpstreamUnavailable)
+}
+
+// do выполняет fn с повторами. Ожидание прерывается отменой контекста.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := p.delay(attempt, err)
+		if p.Budget > 0 && waited+delay > p.Budget {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		waited += delay
+	}
+}
+
+// delay возвращает задержку перед следующей попыткой: Retry-After источника
+// или экспоненциальную задержку со случайным разбросом от половины до полной
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || (p.MaxDelay > 0 && backoff > p.MaxDelay) {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
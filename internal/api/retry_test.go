@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryTestClient клиент CoinGecko с быстрыми повторами для тестов
+func newRetryTestClient(server *httptest.Server, policy RetryPolicy) *CoinGeckoClient {
+	return &CoinGeckoClient{
+		baseURL: server.URL,
+		client:  server.Client(),
+		retry:   policy,
+	}
+}
+
+var fastRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+	Budget:      time.Second,
+}
+
+func TestCoinGeckoClient_GetPrices_TypedErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"Rate limited", http.StatusTooManyRequests, `{"status": {"error_code": 429}}`, ErrRateLimited},
+		{"Server error", http.StatusInternalServerError, ``, ErrUpstreamUnavailable},
+		{"Bad gateway", http.StatusBadGateway, `<html>bad gateway</html>`, ErrUpstreamUnavailable},
+		{"Invalid JSON", http.StatusOK, `invalid json`, ErrBadPayload},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			_, err := newRetryTestClient(server, RetryPolicy{}).GetPrices(context.Background(), []string{"bitcoin"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCoinGeckoClient_GetPrices_RetriesRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"bitcoin": {"usd": 45000}}`))
+	}))
+	defer server.Close()
+
+	prices, err := newRetryTestClient(server, fastRetryPolicy).GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if prices["bitcoin"].USD.String() != "45000" {
+		t.Errorf("Unexpected prices: %+v", prices)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestCoinGeckoClient_GetPrices_RetryLimits(t *testing.T) {
+	t.Run("Max attempts", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := newRetryTestClient(server, fastRetryPolicy).GetPrices(context.Background(), []string{"bitcoin"})
+		if !errors.Is(err, ErrUpstreamUnavailable) {
+			t.Errorf("Expected ErrUpstreamUnavailable, got %v", err)
+		}
+		if requests.Load() != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests.Load())
+		}
+	})
+
+	t.Run("Retry-After exceeds budget", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		_, err := newRetryTestClient(server, fastRetryPolicy).GetPrices(context.Background(), []string{"bitcoin"})
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.RetryAfter != 120*time.Second {
+			t.Errorf("Expected StatusError with RetryAfter 2m, got %v", err)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("Expected 1 request, got %d", requests.Load())
+		}
+	})
+
+	t.Run("Client errors are not retried", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		_, err := newRetryTestClient(server, fastRetryPolicy).GetPrices(context.Background(), []string{"bitcoin"})
+		if err == nil || retryable(err) {
+			t.Errorf("Expected non-retryable error, got %v", err)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("Expected 1 request, got %d", requests.Load())
+		}
+	})
+}
+
+func TestCoinGeckoClient_GetPrices_RetriesClientTimeout(t *testing.T) {
+	// Первый ответ не укладывается в http.Client.Timeout
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`{"bitcoin": {"usd": 45000}}`))
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(server, fastRetryPolicy)
+	client.client.Timeout = 50 * time.Millisecond
+
+	prices, err := client.GetPrices(context.Background(), []string{"bitcoin"})
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+	if prices["bitcoin"].USD.String() != "45000" {
+		t.Errorf("Unexpected prices: %+v", prices)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestCoinGeckoClient_GetPrices_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour, Budget: 10 * time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newRetryTestClient(server, policy).GetPrices(ctx, []string{"bitcoin"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Cancellation took too long: %s", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{"Mon, 01 Jan 2024 12:01:00 GMT", time.Minute},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := parseRetryAfter(tc.value, now); got != tc.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		want := policy.BaseDelay << (attempt - 1)
+		if want > policy.MaxDelay {
+			want = policy.MaxDelay
+		}
+
+		delay := policy.delay(attempt, ErrUpstreamUnavailable)
+		if delay < want/2 || delay > want {
+			t.Errorf("attempt %d: delay %s outside [%s, %s]", attempt, delay, want/2, want)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package bot
 
 import (
+	"context"
 	"cryptorate-service/internal/api"
 	"cryptorate-service/internal/models"
 	"cryptorate-service/internal/repository"
-	"database/sql"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -17,12 +18,12 @@ import (
 type TelegramBot struct {
 	api       *tgbotapi.BotAPI
 	updates   tgbotapi.UpdatesChannel
-	apiClient *api.CoinGeckoClient
-	repo      *repository.Repository
+	apiClient api.PriceProvider
+	repo      *repository.CachedRepository
 }
 
-// Создаем нового бота
-func NewBot(token string, db *sql.DB) (*TelegramBot, error) {
+// Создаем нового бота. Курсы и статистика читаются через кэш repo.
+func NewBot(token string, repo *repository.CachedRepository) (*TelegramBot, error) {
 	botAPI, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
@@ -36,9 +37,17 @@ func NewBot(token string, db *sql.DB) (*TelegramBot, error) {
 
 	updates := botAPI.GetUpdatesChan(u) //Получаем канал сообщений
 
-	// Создаём API клиент и репозиторий
-	apiClient := api.NewCoinGeckoClient()
-	repo := repository.NewRepository(db)
+	// Создаём источник курсов
+	apiClient, err := api.NewProviders(os.Getenv("PRICE_PROVIDER"), api.DefaultMaxDeviation)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := api.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+	api.SetLimits(apiClient, limits, repo)
 
 	return &TelegramBot{
 		api:       botAPI,
@@ -48,14 +57,23 @@ func NewBot(token string, db *sql.DB) (*TelegramBot, error) {
 	}, nil
 }
 
-// Метод для запуски бота
-func (b *TelegramBot) Start() {
-	for update := range b.updates {
+// Метод для запуски бота. Отмена ctx останавливает получение сообщений
+// и прерывает запросы к БД.
+func (b *TelegramBot) Start(ctx context.Context) {
+	for {
+		var update tgbotapi.Update
+		select {
+		case <-ctx.Done():
+			b.api.StopReceivingUpdates()
+			return
+		case update = <-b.updates:
+		}
+
 		if update.Message == nil {
 			continue
 		}
 
-		err := b.repo.EnsureUser(update.Message.Chat.ID, update.Message.From.UserName)
+		err := b.repo.EnsureUser(ctx, update.Message.Chat.ID, update.Message.From.UserName)
 		if err != nil {
 			log.Printf("Error ensuring user: %v", err)
 		}
@@ -69,17 +87,44 @@ func (b *TelegramBot) Start() {
 				"Доступные команды:\n" +
 				"/rates - все курсы\n" +
 				"/rates [валюта] - курс конкретной валюты\n" +
+				"/rates [валюта] [eur|rub|btc] - курс в другой валюте\n" +
 				"/currencies - список всех валют\n" +
 				"/startauto [минуты] - автоотправка\n" +
-				"/stopauto - остановить автоотправку"
+				"/stopauto - остановить автоотправку\n" +
+				"/timezone [пояс] - часовой пояс для дневной статистики"
 
 		case "rates":
-			args := update.Message.CommandArguments()
-			if args == "" {
-				rates, err := b.repo.GetLatestRates()
+			// /rates [валюта] [котировка] или /rates [котировка]
+			args := strings.Fields(strings.ToLower(update.Message.CommandArguments()))
+			quote := models.DefaultQuote
+			var currencyID int
+			var err error
+			if len(args) > 0 {
+				// Пробуем найти по символу (BTC, ETH)
+				currencyID, err = b.repo.GetCurrencyIDBySymbol(ctx, args[0])
+				if err != nil {
+					// Если не нашли по символу, ищем по имени
+					currencyID, err = b.repo.GetCurrencyID(ctx, args[0])
+				}
+				if len(args) > 1 {
+					quote = args[1]
+				} else if err != nil {
+					// Один аргумент, и это не валюта - считаем его котировкой (/rates eur)
+					quote = args[0]
+					args = nil
+				}
+			}
+
+			// Время и дневная статистика - по календарю пользователя
+			loc := b.userLocation(ctx, update.Message.Chat.ID)
+
+			if len(args) == 0 {
+				rates, err := b.repo.GetRateSummaries(ctx, quote, loc)
 				if err != nil {
 					log.Printf("Database error: %v", err)
 					msg.Text = "Ошибка получения курсов"
+				} else if len(rates) == 0 && quote != models.DefaultQuote {
+					msg.Text = "Валюта не найдена. Используйте /currencies для списка"
 				} else if len(rates) == 0 {
 					msg.Text = "Курсов пока нет. Попробуйте позже."
 				} else {
@@ -91,61 +136,83 @@ func (b *TelegramBot) Start() {
 
 					var response strings.Builder
 					response.WriteString("📊 Последние курсы:\n\n")
+					hasStale := false
 					for _, rate := range rates {
-						// Теперь rate.CurrencyID доступен
-						symbol, _ := b.repo.GetCurrencySymbolByID(rate.CurrencyID)
-						timeStr := rate.RecordedAt.Format("15:04")
-						response.WriteString(fmt.Sprintf("• %s (%s): $%.2f (%s)\n",
-							rate.NameCurrency, symbol, rate.Price, timeStr))
+						timeStr := rate.RecordedAt.In(loc).Format("15:04")
+						change24h := ""
+						if rate.Market != nil {
+							change24h = fmt.Sprintf(" %+.2f%%", rate.Market.Change24h)
+						}
+						stale := ""
+						if _, isStale := models.Freshness(rate.RecordedAt, rate.MaxAge, time.Now()); isStale {
+							stale = " ⚠️ устарел"
+							hasStale = true
+						}
+						response.WriteString(fmt.Sprintf("• %s (%s): %s%s (%s)%s\n",
+							rate.NameCurrency, rate.Symbol, formatPrice(rate.Price, quote), change24h, timeStr, stale))
+					}
+					if hasStale {
+						response.WriteString("\n⚠️ Часть курсов давно не обновлялась, данные могут быть неактуальны")
 					}
 					response.WriteString("\n🔄 Обновляется каждые 5 минут")
 					msg.Text = response.String()
 				}
 			} else {
 				// Курс конкретной валюты
-				currencyName := strings.ToLower(args)
-
-				// Пробуем найти по символу (BTC, ETH)
-				currencyID, err := b.repo.GetCurrencyIDBySymbol(currencyName)
-				if err != nil {
-					// Если не нашли по символу, ищем по имени
-					currencyID, err = b.repo.GetCurrencyID(currencyName)
-				}
-
 				if err != nil {
 					msg.Text = "Валюта не найдена. Используйте /currencies для списка"
 				} else {
-					rate, err := b.repo.GetCurrencyRate(currencyID)
-					if err != nil {
+					rates, err := b.repo.GetRateSummaries(ctx, quote, loc, currencyID)
+					if err != nil || len(rates) == 0 {
 						msg.Text = "Ошибка получения курса"
 					} else {
-						min, max, _ := b.repo.GetDailyMinMax(currencyID)
-						change, _ := b.repo.GetHourlyChange(currencyID)
+						rate := rates[0]
+
+						var market string
+						if rate.Market != nil {
+							market = fmt.Sprintf(
+								"📉 24ч: %+.2f%%\n"+
+									"💰 Капитализация: %s\n"+
+									"📦 Объём за 24ч: %s\n",
+								rate.Market.Change24h,
+								formatAmount(rate.Market.MarketCap, quote),
+								formatAmount(rate.Market.Volume24h, quote),
+							)
+						} else {
+							// Источник не отдаёт рыночные показатели - изменение по сохранённым курсам
+							market = fmt.Sprintf("📉 24ч: %+.2f%%\n", rate.Change24h)
+						}
 
-						// Получаем информацию о валюте
-						symbol, _ := b.repo.GetCurrencySymbolByID(currencyID)
-						displayName, _ := b.repo.GetCurrencyDisplayName(currencyID)
+						var stale string
+						if age, isStale := models.Freshness(rate.RecordedAt, rate.MaxAge, time.Now()); isStale {
+							stale = fmt.Sprintf("\n⚠️ Курс устарел: не обновлялся %s", formatAge(age))
+						}
 
 						msg.Text = fmt.Sprintf(
 							"📊 %s (%s)\n"+
-								"💵 Текущий курс: $%.2f\n"+
-								"📈 День: $%.2f - $%.2f\n"+
+								"💵 Текущий курс: %s\n"+
+								"📈 День: %s - %s\n"+
+								"📅 С начала дня: %+.2f%%\n"+
 								"🕐 Час: %.2f%%\n"+
-								"⏰ Обновлено: %s",
-							displayName,
-							symbol,
-							rate.Price,
-							min,
-							max,
-							change,
-							rate.RecordedAt.Format("15:04"),
+								"%s"+
+								"⏰ Обновлено: %s%s",
+							rate.DisplayName,
+							rate.Symbol,
+							formatPrice(rate.Price, quote),
+							formatPrice(rate.DailyMin, quote),
+							formatPrice(rate.DailyMax, quote),
+							rate.DayChange,
+							rate.HourlyChange,
+							market,
+							rate.RecordedAt.In(loc).Format("15:04"),
+							stale,
 						)
 					}
 				}
 			}
 
 		case "currencies":
-			currencies, err := b.repo.GetAllCurrencies()
+			currencies, err := b.repo.GetAllCurrencies(ctx)
 			if err != nil {
 				msg.Text = "Ошибка получения списка валют"
 			} else {
@@ -173,7 +240,7 @@ func (b *TelegramBot) Start() {
 				} else if interval < 5 {
 					msg.Text = "Минимальный интервал - 5 минут"
 				} else {
-					err := b.repo.SetUserInterval(update.Message.Chat.ID, interval)
+					err := b.repo.SetUserInterval(ctx, update.Message.Chat.ID, interval)
 					if err != nil {
 						log.Printf("Error setting interval: %v", err)
 						msg.Text = "Ошибка настройки автоотправки"
@@ -188,8 +255,31 @@ func (b *TelegramBot) Start() {
 				}
 			}
 
+		case "timezone":
+			// /timezone Europe/Moscow - день для статистики считается по этому поясу
+			name := strings.TrimSpace(update.Message.CommandArguments())
+			if name == "" {
+				loc := b.userLocation(ctx, update.Message.Chat.ID)
+				msg.Text = fmt.Sprintf(
+					"🕐 Ваш часовой пояс: %s\n\n"+
+						"Изменить: /timezone Europe/Moscow",
+					loc,
+				)
+			} else if loc, err := models.LoadTimezone(name); err != nil {
+				msg.Text = "Неизвестный часовой пояс. Пример: /timezone Europe/Moscow"
+			} else if err := b.repo.SetUserTimezone(ctx, update.Message.Chat.ID, loc.String()); err != nil {
+				log.Printf("Error setting timezone: %v", err)
+				msg.Text = "Ошибка сохранения часового пояса"
+			} else {
+				msg.Text = fmt.Sprintf(
+					"✅ Часовой пояс: %s\n"+
+						"📈 Дневная статистика считается с полуночи, сейчас %s",
+					loc, time.Now().In(loc).Format("15:04"),
+				)
+			}
+
 		case "stopauto":
-			err := b.repo.StopAuto(update.Message.Chat.ID)
+			err := b.repo.StopAuto(ctx, update.Message.Chat.ID)
 			if err != nil {
 				log.Printf("Error stopping auto: %v", err)
 				msg.Text = "Ошибка отключения автоотправки"
@@ -210,12 +300,12 @@ func (b *TelegramBot) Start() {
 }
 
 // autoSendWorker отправляет автоматические уведомления
-func (b *TelegramBot) autoSendWorker() {
+func (b *TelegramBot) autoSendWorker(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		users, err := b.repo.GetSubscribedUsers()
+		users, err := b.repo.GetSubscribedUsers(ctx)
 		if err != nil {
 			log.Printf("Error getting subscribed users: %v", err)
 			continue
@@ -229,7 +319,11 @@ func (b *TelegramBot) autoSendWorker() {
 
 			if currentTime.After(nextSendTime) {
 				// Формируем сообщение
-				message := b.buildAutoMessage(user.Currencies)
+				loc, err := models.LoadTimezone(user.Timezone)
+				if err != nil {
+					loc = time.UTC
+				}
+				message := b.buildAutoMessage(ctx, user.Currencies, loc)
 				if message != "" {
 					msg := tgbotapi.NewMessage(user.UserID, message)
 
@@ -238,7 +332,7 @@ func (b *TelegramBot) autoSendWorker() {
 						_, err := b.api.Send(msg)
 						if err == nil {
 							// Успешно отправили, обновляем время
-							b.repo.UpdateLastSent(user.UserID)
+							b.repo.UpdateLastSent(ctx, user.UserID)
 							break
 						}
 
@@ -255,8 +349,9 @@ func (b *TelegramBot) autoSendWorker() {
 	}
 }
 
-// buildAutoMessage формирует сообщение для автоотправки
-func (b *TelegramBot) buildAutoMessage(currencies []models.Currency) string {
+// buildAutoMessage формирует сообщение для автоотправки.
+// Дневная статистика и время - в часовом поясе пользователя loc.
+func (b *TelegramBot) buildAutoMessage(ctx context.Context, currencies []models.Currency, loc *time.Location) string {
 	if len(currencies) == 0 {
 		return ""
 	}
@@ -270,35 +365,120 @@ func (b *TelegramBot) buildAutoMessage(currencies []models.Currency) string {
 		currencies = currencies[:maxCurrencies]
 	}
 
-	for _, currency := range currencies {
-		currencyID, err := b.repo.GetCurrencyID(currency.NameCurrency)
-		if err != nil {
-			continue
-		}
+	// Курсы всех валют сообщения одним запросом
+	ids := make([]int, len(currencies))
+	for i, currency := range currencies {
+		ids[i] = currency.ID
+	}
+	rates, err := b.repo.GetRateSummaries(ctx, models.DefaultQuote, loc, ids...)
+	if err != nil {
+		log.Printf("Error getting rates: %v", err)
+		return ""
+	}
+	byID := make(map[int]models.RateSummary, len(rates))
+	for _, rate := range rates {
+		byID[rate.CurrencyID] = rate
+	}
 
-		rate, err := b.repo.GetCurrencyRate(currencyID)
-		if err != nil {
+	for _, currency := range currencies {
+		rate, ok := byID[currency.ID]
+		if !ok {
 			continue
 		}
 
-		min, max, _ := b.repo.GetDailyMinMax(currencyID)
-		change, _ := b.repo.GetHourlyChange(currencyID)
-
 		builder.WriteString(fmt.Sprintf(
-			"• %s (%s): $%.2f\n"+
-				"  📊 День: $%.2f - $%.2f\n"+
+			"• %s (%s): %s\n"+
+				"  📊 День: %s - %s\n"+
 				"  📈 Час: %.2f%%\n\n",
 			currency.DisplayName,
 			currency.Symbol,
-			rate.Price,
-			min,
-			max,
-			change,
+			formatPrice(rate.Price, models.DefaultQuote),
+			formatPrice(rate.DailyMin, models.DefaultQuote),
+			formatPrice(rate.DailyMax, models.DefaultQuote),
+			rate.HourlyChange,
 		))
 	}
 
-	builder.WriteString("⏰ " + time.Now().Format("15:04"))
+	builder.WriteString("⏰ " + time.Now().In(loc).Format("15:04"))
 	builder.WriteString("\n💡 /stop-auto для отключения")
 
 	return builder.String()
 }
+
+// userLocation возвращает часовой пояс пользователя, по умолчанию UTC
+func (b *TelegramBot) userLocation(ctx context.Context, userID int64) *time.Location {
+	name, err := b.repo.GetUserTimezone(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting timezone: %v", err)
+	}
+	loc, err := models.LoadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// quoteSigns знаки валют котировки для сообщений
+var quoteSigns = map[string]string{
+	"usd": "$",
+	"eur": "€",
+	"rub": "₽",
+}
+
+// formatPrice форматирует цену с учётом валюты котировки: $45000.50, 0.05500000 BTC.
+// Цена, которая при округлении превратилась бы в ноль, выводится полностью: $0.00001234.
+func formatPrice(price models.Decimal, quote string) string {
+	sign, fiat := quoteSigns[quote]
+	// Котировки в криптовалюте требуют больше знаков после запятой
+	places := 8
+	if fiat {
+		places = 2
+	}
+
+	text := price.StringFixed(places)
+	if rounded, _ := models.ParseDecimal(text); rounded.IsZero() && !price.IsZero() {
+		text = price.String()
+	}
+
+	if fiat {
+		return sign + text
+	}
+	return text + " " + strings.ToUpper(quote)
+}
+
+// formatAmount форматирует крупные суммы с сокращением: $1.23 трлн, $850.40 млрд
+func formatAmount(amount float64, quote string) string {
+	scales := []struct {
+		value float64
+		unit  string
+	}{
+		{1e12, "трлн"},
+		{1e9, "млрд"},
+		{1e6, "млн"},
+	}
+
+	value, unit := amount, ""
+	for _, scale := range scales {
+		if amount >= scale.value {
+			value, unit = amount/scale.value, " "+scale.unit
+			break
+		}
+	}
+
+	if sign, ok := quoteSigns[quote]; ok {
+		return fmt.Sprintf("%s%.2f%s", sign, value, unit)
+	}
+	return fmt.Sprintf("%.2f%s %s", value, unit, strings.ToUpper(quote))
+}
+
+// formatAge выводит возраст курса: 25 мин, 3 ч 10 мин
+func formatAge(age time.Duration) string {
+	minutes := int(age.Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%d мин", minutes)
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%d ч", minutes/60)
+	}
+	return fmt.Sprintf("%d ч %d мин", minutes/60, minutes%60)
+}
@@ -0,0 +1,182 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoad_Embedded(t *testing.T) {
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	// Версии идут подряд с 1, пропуск обычно означает потерянный файл
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Expected version %d, got %d (%s)", i+1, migration.Version, migration.Name)
+		}
+	}
+	if migrations[0].Name != "init" {
+		t.Errorf("Expected first migration 'init', got '%s'", migrations[0].Name)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"bad file name", fstest.MapFS{"sql/init.sql": {Data: []byte("SELECT 1")}}},
+		{"missing down", fstest.MapFS{"sql/0001_init.up.sql": {Data: []byte("SELECT 1")}}},
+		{"different names", fstest.MapFS{
+			"sql/0001_init.up.sql":    {Data: []byte("SELECT 1")},
+			"sql/0001_other.down.sql": {Data: []byte("SELECT 1")},
+		}},
+	}
+
+	for _, tt := range tests {
+		if _, err := load(tt.fsys); err == nil {
+			t.Errorf("%s: expected error, got nil", tt.name)
+		}
+	}
+}
+
+func testMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	fsys := fstest.MapFS{
+		"sql/0001_init.up.sql":      {Data: []byte("CREATE TABLE currency (id SERIAL PRIMARY KEY)")},
+		"sql/0001_init.down.sql":    {Data: []byte("DROP TABLE currency")},
+		"sql/0002_max_age.up.sql":   {Data: []byte("ALTER TABLE currency ADD COLUMN max_age_seconds INTEGER")},
+		"sql/0002_max_age.down.sql": {Data: []byte("ALTER TABLE currency DROP COLUMN max_age_seconds")},
+	}
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	return &Migrator{db: db, migrations: migrations}, mock
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int) {
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(rows)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	migrator, mock := testMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectApplied(mock, 1)
+	// Применяется только вторая миграция
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE currency ADD COLUMN max_age_seconds INTEGER`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, name, applied_at\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs(2, "max_age", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	count, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 applied migration, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_Up_Failure(t *testing.T) {
+	migrator, mock := testMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectApplied(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE currency`).WillReturnError(context.DeadlineExceeded)
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	count, err := migrator.Up(context.Background())
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 applied migrations, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_Down(t *testing.T) {
+	migrator, mock := testMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectApplied(mock, 1, 2)
+	// Откатывается последняя применённая миграция
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE currency DROP COLUMN max_age_seconds`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	count, err := migrator.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 rolled back migration, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	migrator, mock := testMigrator(t)
+
+	expectApplied(mock, 1)
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses, got %d", len(statuses))
+	}
+	if statuses[0].AppliedAt == nil {
+		t.Error("Expected migration 1 to be applied")
+	}
+	if statuses[1].AppliedAt != nil {
+		t.Error("Expected migration 2 to be pending")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS Exchange_rate;
+DROP TABLE IF EXISTS Currency_settings;
+DROP TABLE IF EXISTS Currency;
+DROP TABLE IF EXISTS Settings;
+DROP TABLE IF EXISTS Users;
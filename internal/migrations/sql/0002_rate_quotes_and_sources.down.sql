@@ -0,0 +1,3 @@
+ALTER TABLE Exchange_rate DROP COLUMN IF EXISTS rejected_count;
+ALTER TABLE Exchange_rate DROP COLUMN IF EXISTS sources;
+ALTER TABLE Exchange_rate DROP COLUMN IF EXISTS quote_currency;
@@ -0,0 +1,4 @@
+-- Курсы в нескольких валютах котировки и от нескольких источников
+ALTER TABLE Exchange_rate ADD COLUMN IF NOT EXISTS quote_currency VARCHAR(10) NOT NULL DEFAULT 'usd'; -- валюта котировки (usd, eur, rub, btc)
+ALTER TABLE Exchange_rate ADD COLUMN IF NOT EXISTS sources VARCHAR(255); -- источники, из которых получен курс (coingecko,binance)
+ALTER TABLE Exchange_rate ADD COLUMN IF NOT EXISTS rejected_count INTEGER NOT NULL DEFAULT 0; -- сколько котировок отброшено как выбросы
@@ -0,0 +1,2 @@
+ALTER TABLE Currency DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE Currency DROP COLUMN IF EXISTS is_tracked;
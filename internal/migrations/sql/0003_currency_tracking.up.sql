@@ -0,0 +1,3 @@
+-- Управление списком валют без перезапуска воркера
+ALTER TABLE Currency ADD COLUMN IF NOT EXISTS is_tracked BOOLEAN NOT NULL DEFAULT true; -- загружает ли воркер курсы этой валюты
+ALTER TABLE Currency ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP; -- мягкое удаление: история курсов сохраняется
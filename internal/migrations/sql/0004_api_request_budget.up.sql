@@ -0,0 +1,7 @@
+-- Общий для бота, воркера и backfill счётчик запросов к внешним API по минутам
+CREATE TABLE IF NOT EXISTS Api_request_budget (
+provider VARCHAR(50) NOT NULL,
+window_start TIMESTAMP NOT NULL,
+requests INTEGER NOT NULL DEFAULT 0,
+PRIMARY KEY (provider, window_start)
+);
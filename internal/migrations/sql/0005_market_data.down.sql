@@ -0,0 +1,3 @@
+ALTER TABLE Exchange_rate DROP COLUMN IF EXISTS change_24h;
+ALTER TABLE Exchange_rate DROP COLUMN IF EXISTS volume_24h;
+ALTER TABLE Exchange_rate DROP COLUMN IF EXISTS market_cap;
@@ -0,0 +1,4 @@
+-- Рыночные показатели вместе с курсом, если источник их отдаёт
+ALTER TABLE Exchange_rate ADD COLUMN IF NOT EXISTS market_cap NUMERIC(24, 2); -- капитализация в валюте котировки
+ALTER TABLE Exchange_rate ADD COLUMN IF NOT EXISTS volume_24h NUMERIC(24, 2); -- объём торгов за 24 часа
+ALTER TABLE Exchange_rate ADD COLUMN IF NOT EXISTS change_24h NUMERIC(10, 4); -- изменение цены за 24 часа, %
@@ -0,0 +1,14 @@
+-- Журнал циклов загрузки курсов воркером
+CREATE TABLE IF NOT EXISTS Fetch_runs (
+id SERIAL PRIMARY KEY,
+started_at TIMESTAMP NOT NULL,
+finished_at TIMESTAMP, -- NULL, пока цикл идёт или если воркер упал
+provider VARCHAR(255) NOT NULL,
+coins_requested INTEGER NOT NULL DEFAULT 0,
+rates_saved INTEGER NOT NULL DEFAULT 0,
+coins_skipped INTEGER NOT NULL DEFAULT 0,
+status VARCHAR(20) NOT NULL, -- running, success, partial, failed, skipped
+error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_fetch_runs_started_at ON Fetch_runs (started_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE Currency DROP COLUMN IF EXISTS max_age_seconds;
@@ -0,0 +1,2 @@
+-- Допустимый возраст курса, NULL - значение по умолчанию (15 минут)
+ALTER TABLE Currency ADD COLUMN IF NOT EXISTS max_age_seconds INTEGER;
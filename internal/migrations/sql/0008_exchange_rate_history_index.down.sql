@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_exchange_rate_currency_quote_time;
@@ -0,0 +1,2 @@
+-- Выборка истории курса валюты за период
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_currency_quote_time ON Exchange_rate (currency_id, quote_currency, recorded_at, id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_exchange_rate_recorded_at;
+DROP TABLE IF EXISTS Exchange_rate_daily;
+DROP TABLE IF EXISTS Exchange_rate_hourly;
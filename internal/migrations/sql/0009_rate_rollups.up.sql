@@ -0,0 +1,30 @@
+-- Часовые и дневные свечи курсов. Поддерживаются воркером и остаются
+-- после удаления сырых курсов из Exchange_rate по сроку хранения.
+CREATE TABLE IF NOT EXISTS Exchange_rate_hourly (
+currency_id INTEGER NOT NULL,
+quote_currency VARCHAR(10) NOT NULL,
+bucket TIMESTAMP NOT NULL, -- начало часа
+open DECIMAL(15, 6) NOT NULL,
+high DECIMAL(15, 6) NOT NULL,
+low DECIMAL(15, 6) NOT NULL,
+close DECIMAL(15, 6) NOT NULL,
+samples INTEGER NOT NULL, -- число сырых курсов за час
+PRIMARY KEY (currency_id, quote_currency, bucket),
+FOREIGN KEY (currency_id) REFERENCES Currency(id)
+);
+
+CREATE TABLE IF NOT EXISTS Exchange_rate_daily (
+currency_id INTEGER NOT NULL,
+quote_currency VARCHAR(10) NOT NULL,
+bucket TIMESTAMP NOT NULL, -- полночь UTC
+open DECIMAL(15, 6) NOT NULL,
+high DECIMAL(15, 6) NOT NULL,
+low DECIMAL(15, 6) NOT NULL,
+close DECIMAL(15, 6) NOT NULL,
+samples INTEGER NOT NULL,
+PRIMARY KEY (currency_id, quote_currency, bucket),
+FOREIGN KEY (currency_id) REFERENCES Currency(id)
+);
+
+-- Пересчёт агрегатов и удаление устаревших курсов выбирают курсы по времени
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_recorded_at ON Exchange_rate (recorded_at);
@@ -0,0 +1,29 @@
+ALTER TABLE Exchange_rate RENAME TO Exchange_rate_partitioned;
+ALTER INDEX exchange_rate_pkey RENAME TO exchange_rate_partitioned_pkey;
+ALTER INDEX IF EXISTS idx_exchange_rate_currency_quote_time RENAME TO idx_exchange_rate_partitioned_currency_quote_time;
+ALTER INDEX IF EXISTS idx_exchange_rate_recorded_at RENAME TO idx_exchange_rate_partitioned_recorded_at;
+
+CREATE TABLE Exchange_rate (
+id INTEGER NOT NULL DEFAULT nextval('exchange_rate_id_seq') PRIMARY KEY,
+currency_id INTEGER NOT NULL,
+price DECIMAL(15, 6) NOT NULL,
+recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+quote_currency VARCHAR(10) NOT NULL DEFAULT 'usd',
+sources VARCHAR(255),
+rejected_count INTEGER NOT NULL DEFAULT 0,
+market_cap NUMERIC(24, 2),
+volume_24h NUMERIC(24, 2),
+change_24h NUMERIC(10, 4),
+FOREIGN KEY (currency_id) REFERENCES Currency(id)
+);
+
+INSERT INTO Exchange_rate (id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h)
+SELECT id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h
+FROM Exchange_rate_partitioned;
+
+ALTER SEQUENCE exchange_rate_id_seq OWNED BY Exchange_rate.id;
+DROP TABLE Exchange_rate_partitioned;
+DROP FUNCTION IF EXISTS ensure_exchange_rate_partition(TIMESTAMP);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_currency_quote_time ON Exchange_rate (currency_id, quote_currency, recorded_at, id);
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_recorded_at ON Exchange_rate (recorded_at);
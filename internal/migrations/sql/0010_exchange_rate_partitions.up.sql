@@ -0,0 +1,60 @@
+-- Exchange_rate разбивается на месячные секции по recorded_at.
+-- Секции на следующие месяцы создаёт воркер через ensure_exchange_rate_partition,
+-- курсы вне созданных секций попадают в Exchange_rate_default.
+ALTER TABLE Exchange_rate RENAME TO Exchange_rate_old;
+ALTER INDEX exchange_rate_pkey RENAME TO exchange_rate_old_pkey;
+ALTER INDEX IF EXISTS idx_exchange_rate_currency_quote_time RENAME TO idx_exchange_rate_old_currency_quote_time;
+ALTER INDEX IF EXISTS idx_exchange_rate_recorded_at RENAME TO idx_exchange_rate_old_recorded_at;
+
+CREATE TABLE Exchange_rate (
+id INTEGER NOT NULL DEFAULT nextval('exchange_rate_id_seq'),
+currency_id INTEGER NOT NULL,
+price DECIMAL(15, 6) NOT NULL,
+recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+quote_currency VARCHAR(10) NOT NULL DEFAULT 'usd', -- валюта котировки (usd, eur, rub, btc)
+sources VARCHAR(255), -- источники, из которых получен курс (coingecko,binance)
+rejected_count INTEGER NOT NULL DEFAULT 0, -- сколько котировок отброшено как выбросы
+market_cap NUMERIC(24, 2), -- капитализация в валюте котировки
+volume_24h NUMERIC(24, 2), -- объём торгов за 24 часа
+change_24h NUMERIC(10, 4), -- изменение цены за 24 часа, %
+PRIMARY KEY (id, recorded_at), -- ключ секционированной таблицы включает ключ секционирования
+FOREIGN KEY (currency_id) REFERENCES Currency(id)
+) PARTITION BY RANGE (recorded_at);
+
+CREATE TABLE Exchange_rate_default PARTITION OF Exchange_rate DEFAULT;
+
+-- Создаёт секцию месяца, в который попадает month_start.
+-- Возвращает false, если секция уже есть.
+CREATE OR REPLACE FUNCTION ensure_exchange_rate_partition(month_start TIMESTAMP) RETURNS BOOLEAN AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', month_start);
+    partition_name TEXT := 'exchange_rate_' || to_char(start_at, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+    EXECUTE format('CREATE TABLE %I PARTITION OF Exchange_rate FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_at, start_at + INTERVAL '1 month');
+    RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Секции для уже накопленных курсов и на два месяца вперёд
+SELECT ensure_exchange_rate_partition(month_start)
+FROM generate_series(
+    date_trunc('month', COALESCE((SELECT MIN(recorded_at) FROM Exchange_rate_old), LOCALTIMESTAMP)),
+    date_trunc('month', LOCALTIMESTAMP) + INTERVAL '2 months',
+    INTERVAL '1 month'
+) AS month_start;
+
+INSERT INTO Exchange_rate (id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h)
+SELECT id, currency_id, price, COALESCE(recorded_at, LOCALTIMESTAMP), quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h
+FROM Exchange_rate_old;
+
+ALTER SEQUENCE exchange_rate_id_seq OWNED BY Exchange_rate.id;
+DROP TABLE Exchange_rate_old;
+
+-- Индексы создаются на каждой секции: последний курс и история валюты
+-- читаются обратным проходом по индексу, без сортировки таблицы
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_currency_quote_time ON Exchange_rate (currency_id, quote_currency, recorded_at, id);
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_recorded_at ON Exchange_rate (recorded_at);
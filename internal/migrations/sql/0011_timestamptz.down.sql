@@ -0,0 +1,76 @@
+ALTER TABLE Users DROP COLUMN IF EXISTS timezone;
+
+ALTER TABLE Exchange_rate_daily ALTER COLUMN bucket TYPE TIMESTAMP USING bucket AT TIME ZONE 'UTC';
+ALTER TABLE Exchange_rate_hourly ALTER COLUMN bucket TYPE TIMESTAMP USING bucket AT TIME ZONE 'UTC';
+
+CREATE TEMP TABLE exchange_rate_partitions ON COMMIT DROP AS
+SELECT c.relname::TEXT AS name
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'exchange_rate'::regclass;
+
+DO $$
+DECLARE
+    partition_name TEXT;
+BEGIN
+    FOR partition_name IN SELECT name FROM exchange_rate_partitions LOOP
+        EXECUTE format('ALTER TABLE Exchange_rate DETACH PARTITION %I', partition_name);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN recorded_at TYPE TIMESTAMP USING recorded_at AT TIME ZONE ''UTC''', partition_name);
+    END LOOP;
+END $$;
+
+ALTER TABLE Exchange_rate RENAME TO Exchange_rate_new;
+ALTER INDEX exchange_rate_pkey RENAME TO exchange_rate_new_pkey;
+ALTER INDEX idx_exchange_rate_currency_quote_time RENAME TO idx_exchange_rate_new_currency_quote_time;
+ALTER INDEX idx_exchange_rate_recorded_at RENAME TO idx_exchange_rate_new_recorded_at;
+
+CREATE TABLE Exchange_rate (
+id INTEGER NOT NULL DEFAULT nextval('exchange_rate_id_seq'),
+currency_id INTEGER NOT NULL,
+price DECIMAL(15, 6) NOT NULL,
+recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+quote_currency VARCHAR(10) NOT NULL DEFAULT 'usd',
+sources VARCHAR(255),
+rejected_count INTEGER NOT NULL DEFAULT 0,
+market_cap NUMERIC(24, 2),
+volume_24h NUMERIC(24, 2),
+change_24h NUMERIC(10, 4),
+PRIMARY KEY (id, recorded_at),
+FOREIGN KEY (currency_id) REFERENCES Currency(id)
+) PARTITION BY RANGE (recorded_at);
+
+CREATE INDEX idx_exchange_rate_currency_quote_time ON Exchange_rate (currency_id, quote_currency, recorded_at, id);
+CREATE INDEX idx_exchange_rate_recorded_at ON Exchange_rate (recorded_at);
+
+ALTER SEQUENCE exchange_rate_id_seq OWNED BY Exchange_rate.id;
+DROP TABLE Exchange_rate_new;
+
+DO $$
+DECLARE
+    partition_name TEXT;
+    start_at TIMESTAMP;
+BEGIN
+    FOR partition_name IN SELECT name FROM exchange_rate_partitions WHERE name <> 'exchange_rate_default' LOOP
+        start_at := make_timestamp(split_part(partition_name, '_', 3)::INT, split_part(partition_name, '_', 4)::INT, 1, 0, 0, 0);
+        EXECUTE format('ALTER TABLE Exchange_rate ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+            partition_name, start_at, start_at + INTERVAL '1 month');
+    END LOOP;
+END $$;
+
+ALTER TABLE Exchange_rate ATTACH PARTITION Exchange_rate_default DEFAULT;
+
+DROP FUNCTION IF EXISTS ensure_exchange_rate_partition(TIMESTAMPTZ);
+
+CREATE OR REPLACE FUNCTION ensure_exchange_rate_partition(month_start TIMESTAMP) RETURNS BOOLEAN AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', month_start);
+    partition_name TEXT := 'exchange_rate_' || to_char(start_at, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+    EXECUTE format('CREATE TABLE %I PARTITION OF Exchange_rate FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_at, start_at + INTERVAL '1 month');
+    RETURN true;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,14 @@
+-- Цены мельче 0.000001 округляются, цены больше 10^9 не помещаются и прерывают откат
+ALTER TABLE Exchange_rate_daily
+    ALTER COLUMN open TYPE DECIMAL(15, 6),
+    ALTER COLUMN high TYPE DECIMAL(15, 6),
+    ALTER COLUMN low TYPE DECIMAL(15, 6),
+    ALTER COLUMN close TYPE DECIMAL(15, 6);
+
+ALTER TABLE Exchange_rate_hourly
+    ALTER COLUMN open TYPE DECIMAL(15, 6),
+    ALTER COLUMN high TYPE DECIMAL(15, 6),
+    ALTER COLUMN low TYPE DECIMAL(15, 6),
+    ALTER COLUMN close TYPE DECIMAL(15, 6);
+
+ALTER TABLE Exchange_rate ALTER COLUMN price TYPE DECIMAL(15, 6);
@@ -0,0 +1,16 @@
+-- Цены хранятся с 18 знаками после запятой: курсы мельче цента (SHIB ~ 0.00001 USD,
+-- в котировке BTC ещё на пять порядков меньше) в DECIMAL(15, 6) округлялись до нуля.
+-- Изменение типа родительской таблицы распространяется на все секции.
+ALTER TABLE Exchange_rate ALTER COLUMN price TYPE NUMERIC(38, 18);
+
+ALTER TABLE Exchange_rate_hourly
+    ALTER COLUMN open TYPE NUMERIC(38, 18),
+    ALTER COLUMN high TYPE NUMERIC(38, 18),
+    ALTER COLUMN low TYPE NUMERIC(38, 18),
+    ALTER COLUMN close TYPE NUMERIC(38, 18);
+
+ALTER TABLE Exchange_rate_daily
+    ALTER COLUMN open TYPE NUMERIC(38, 18),
+    ALTER COLUMN high TYPE NUMERIC(38, 18),
+    ALTER COLUMN low TYPE NUMERIC(38, 18),
+    ALTER COLUMN close TYPE NUMERIC(38, 18);
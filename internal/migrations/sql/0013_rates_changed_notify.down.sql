@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS currency_changed ON Currency;
+DROP TRIGGER IF EXISTS exchange_rate_changed ON Exchange_rate;
+DROP FUNCTION IF EXISTS notify_rates_changed();
@@ -0,0 +1,18 @@
+-- Уведомление в канал rates_changed о новых курсах и изменении валют.
+-- API и бот держат кэш последних курсов и сбрасывают его по уведомлению.
+-- Триггер уровня оператора: многострочный INSERT цикла воркера даёт одно
+-- уведомление, а одинаковые уведомления одной транзакции PostgreSQL объединяет.
+CREATE OR REPLACE FUNCTION notify_rates_changed() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('rates_changed', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER exchange_rate_changed
+AFTER INSERT ON Exchange_rate
+FOR EACH STATEMENT EXECUTE FUNCTION notify_rates_changed();
+
+CREATE TRIGGER currency_changed
+AFTER INSERT OR UPDATE OR DELETE ON Currency
+FOR EACH STATEMENT EXECUTE FUNCTION notify_rates_changed();
@@ -0,0 +1,3 @@
+-- Не применится, если валюта была добавлена заново после удаления
+DROP INDEX IF EXISTS idx_currency_name_active;
+ALTER TABLE Currency ADD CONSTRAINT currency_name_currency_key UNIQUE (name_currency);
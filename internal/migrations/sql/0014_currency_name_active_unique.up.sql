@@ -0,0 +1,4 @@
+-- Имя уникально только среди неудалённых валют: удалённую валюту можно
+-- добавить заново, её строка с историей курсов остаётся
+ALTER TABLE Currency DROP CONSTRAINT IF EXISTS currency_name_currency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_name_active ON Currency (name_currency) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Значения мельче 0.01 округляются, значения больше 10^22 не помещаются и прерывают откат
+ALTER TABLE Exchange_rate
+    ALTER COLUMN market_cap TYPE NUMERIC(24, 2),
+    ALTER COLUMN volume_24h TYPE NUMERIC(24, 2);
@@ -0,0 +1,6 @@
+-- Рыночные показатели без ограничения точности: в котировке BTC или ETH
+-- объём торгов бывает меньше 0.01 и в NUMERIC(24, 2) округлялся до нуля.
+-- Изменение типа родительской таблицы распространяется на все секции.
+ALTER TABLE Exchange_rate
+    ALTER COLUMN market_cap TYPE NUMERIC,
+    ALTER COLUMN volume_24h TYPE NUMERIC;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS Exchange_rate_retention;
@@ -0,0 +1,17 @@
+-- Граница удалённых сырых курсов. Часы раньше неё агрегированы по курсам,
+-- которых в Exchange_rate уже нет, поэтому пересчёт после backfill
+-- не перезаписывает их агрегаты.
+CREATE TABLE IF NOT EXISTS Exchange_rate_retention (
+id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id), -- таблица из одной строки
+pruned_before TIMESTAMPTZ NOT NULL
+);
+
+-- Курсы могли удаляться и до этой миграции: границей считается час
+-- самого раннего сырого курса, а без курсов - конец агрегатов
+INSERT INTO Exchange_rate_retention (pruned_before)
+SELECT COALESCE(
+    (SELECT date_trunc('hour', MIN(recorded_at), 'UTC') FROM Exchange_rate),
+    (SELECT MAX(bucket) + INTERVAL '1 hour' FROM Exchange_rate_hourly)
+)
+WHERE EXISTS (SELECT 1 FROM Exchange_rate_hourly)
+ON CONFLICT (id) DO NOTHING;
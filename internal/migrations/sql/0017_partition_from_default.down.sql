@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION ensure_exchange_rate_partition(month_start TIMESTAMPTZ) RETURNS BOOLEAN AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', month_start AT TIME ZONE 'UTC');
+    partition_name TEXT := 'exchange_rate_' || to_char(start_at, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+    EXECUTE format('CREATE TABLE %I PARTITION OF Exchange_rate FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_at AT TIME ZONE 'UTC', (start_at + INTERVAL '1 month') AT TIME ZONE 'UTC');
+    RETURN true;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,40 @@
+-- Курсы месяца без секции попадают в Exchange_rate_default, и после этого
+-- CREATE TABLE ... PARTITION OF для месяца завершается ошибкой. Функция
+-- переносит такие курсы из Exchange_rate_default в создаваемую секцию.
+CREATE OR REPLACE FUNCTION ensure_exchange_rate_partition(month_start TIMESTAMPTZ) RETURNS BOOLEAN AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', month_start AT TIME ZONE 'UTC');
+    partition_name TEXT := 'exchange_rate_' || to_char(start_at, 'YYYY_MM');
+    lower_bound TIMESTAMPTZ := start_at AT TIME ZONE 'UTC';
+    upper_bound TIMESTAMPTZ := (start_at + INTERVAL '1 month') AT TIME ZONE 'UTC';
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+
+    -- Создание секции всё равно блокирует Exchange_rate_default целиком,
+    -- блокировка заранее не даёт новым курсам месяца попасть в него
+    -- во время переноса
+    LOCK TABLE Exchange_rate_default IN ACCESS EXCLUSIVE MODE;
+
+    CREATE TEMP TABLE exchange_rate_moved (LIKE Exchange_rate);
+    WITH moved AS (
+        DELETE FROM Exchange_rate_default
+        WHERE recorded_at >= lower_bound AND recorded_at < upper_bound
+        RETURNING id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h
+    )
+    INSERT INTO exchange_rate_moved (id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h)
+    SELECT id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h
+    FROM moved;
+
+    EXECUTE format('CREATE TABLE %I PARTITION OF Exchange_rate FOR VALUES FROM (%L) TO (%L)',
+        partition_name, lower_bound, upper_bound);
+
+    INSERT INTO Exchange_rate (id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h)
+    SELECT id, currency_id, price, recorded_at, quote_currency, sources, rejected_count, market_cap, volume_24h, change_24h
+    FROM exchange_rate_moved;
+    DROP TABLE exchange_rate_moved;
+
+    RETURN true;
+END;
+$$ LANGUAGE plpgsql;
@@ -1,35 +1,340 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultQuote валюта котировки по умолчанию
+const DefaultQuote = "usd"
+
+// DefaultMaxAge допустимый возраст курса, если для валюты не задан свой
+const DefaultMaxAge = 15 * time.Minute
 
 type Currency struct {
 	ID           int    `json:"id"`
 	NameCurrency string `json:"name_currency"`
 	DisplayName  string `json:"display_name"`
-	Symbol       string `json:"symbol"` 
+	Symbol       string `json:"symbol"`
+	IsTracked    bool   `json:"is_tracked"`
+	// MaxAge допустимый возраст курса, ноль - DefaultMaxAge
+	MaxAge time.Duration `json:"-"`
+}
+
+// CurrencyUpdate частичное изменение валюты, nil поля не меняются
+type CurrencyUpdate struct {
+	NameCurrency *string
+	DisplayName  *string
+	Symbol       *string
+	IsTracked    *bool
+	MaxAge       *time.Duration
 }
 
 type ExchangeRate struct {
-	ID         int     `json:"id"`
-	CurrencyID int     `json:"currency_id"`
-	Price      float64 `json:"price"`
-	RecordedAt time.Time `json:"recorded_at"` 
+	ID         int         `json:"id"`
+	CurrencyID int         `json:"currency_id"`
+	Price      Decimal     `json:"price"`
+	Quote      string      `json:"quote"`
+	RecordedAt time.Time   `json:"recorded_at"`
+	Sources    []string    `json:"sources,omitempty"`
+	Rejected   int         `json:"rejected,omitempty"`
+	Market     *MarketData `json:"market,omitempty"`
+	// MaxAge допустимый возраст курса для этой валюты, ноль - DefaultMaxAge
+	MaxAge time.Duration `json:"-"`
+}
+
+// MarketData рыночные показатели валюты в одной валюте котировки.
+// Change24h - изменение цены за 24 часа в процентах.
+type MarketData struct {
+	MarketCap float64 `json:"market_cap"`
+	Volume24h float64 `json:"volume_24h"`
+	Change24h float64 `json:"change_24h"`
+}
+
+// PricePoint курс валюты в конкретный момент времени (исторические данные)
+type PricePoint struct {
+	Time  time.Time `json:"time"`
+	Price Decimal   `json:"price"`
+}
+
+// HistoryQuery выборка курсов валюты за период [From, To) по возрастанию времени.
+// After продолжает выборку после последней точки предыдущей страницы.
+type HistoryQuery struct {
+	CurrencyID int
+	Quote      string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	After      *HistoryCursor
+}
+
+// HistoryCursor позиция в истории курсов: время и ID записи,
+// ID различает записи с одинаковым временем
+type HistoryCursor struct {
+	Time time.Time
+	ID   int
+}
+
+// HistoryPoint сохранённый курс из истории
+type HistoryPoint struct {
+	ID    int       `json:"-"`
+	Time  time.Time `json:"time"`
+	Price Decimal   `json:"price"`
+}
+
+// CandleQuery выборка свечей валюты за период [From, To) с шагом Interval
+type CandleQuery struct {
+	CurrencyID int
+	Quote      string
+	From       time.Time
+	To         time.Time
+	Interval   time.Duration
 }
 
-type CoinGeckoResponse map[string]struct {
-	USD float64 `json:"usd"`
+// Candle свеча OHLC за интервал, начинающийся в Time.
+// Samples - число курсов в интервале, малое значение означает пропуски загрузки.
+type Candle struct {
+	Time    time.Time `json:"time"`
+	Open    Decimal   `json:"open"`
+	High    Decimal   `json:"high"`
+	Low     Decimal   `json:"low"`
+	Close   Decimal   `json:"close"`
+	Samples int       `json:"samples"`
+}
+
+type CoinGeckoResponse map[string]PriceQuote
+
+// PriceQuote курсы одной валюты от источника во всех запрошенных валютах котировки.
+// Market заполняется, если источник отдаёт рыночные показатели.
+// Sources и Rejected заполняются по каждой валюте котировки при агрегации
+// нескольких источников.
+type PriceQuote struct {
+	USD      Decimal               `json:"usd"`
+	Prices   map[string]Decimal    `json:"-"`
+	Market   map[string]MarketData `json:"-"`
+	Sources  map[string][]string   `json:"-"`
+	Rejected map[string]int        `json:"-"`
+}
+
+// Price возвращает курс в указанной валюте котировки (usd, eur, btc)
+func (q PriceQuote) Price(quote string) (Decimal, bool) {
+	quote = strings.ToLower(quote)
+	if price, ok := q.Prices[quote]; ok {
+		return price, true
+	}
+	if quote == DefaultQuote && !q.USD.IsZero() {
+		return q.USD, true
+	}
+	return Decimal{}, false
+}
+
+// SetPrice сохраняет курс в указанной валюте котировки
+func (q *PriceQuote) SetPrice(quote string, price Decimal) {
+	quote = strings.ToLower(quote)
+	if q.Prices == nil {
+		q.Prices = make(map[string]Decimal)
+	}
+	q.Prices[quote] = price
+	if quote == DefaultQuote {
+		q.USD = price
+	}
+}
+
+// MarketData возвращает рыночные показатели в указанной валюте котировки
+func (q PriceQuote) MarketData(quote string) (MarketData, bool) {
+	market, ok := q.Market[strings.ToLower(quote)]
+	return market, ok
+}
+
+// SetMarket сохраняет рыночные показатели в указанной валюте котировки
+func (q *PriceQuote) SetMarket(quote string, market MarketData) {
+	if q.Market == nil {
+		q.Market = make(map[string]MarketData)
+	}
+	q.Market[strings.ToLower(quote)] = market
+}
+
+// QuoteSources возвращает источники, принятые в указанной валюте котировки,
+// и число отброшенных котировок
+func (q PriceQuote) QuoteSources(quote string) ([]string, int) {
+	quote = strings.ToLower(quote)
+	return q.Sources[quote], q.Rejected[quote]
+}
+
+// SetSources сохраняет принятые источники и число отброшенных котировок
+// в указанной валюте котировки
+func (q *PriceQuote) SetSources(quote string, sources []string, rejected int) {
+	quote = strings.ToLower(quote)
+	if q.Sources == nil {
+		q.Sources = make(map[string][]string)
+		q.Rejected = make(map[string]int)
+	}
+	q.Sources[quote] = sources
+	q.Rejected[quote] = rejected
+}
+
+// Суффиксы рыночных показателей в ответе CoinGecko (usd_market_cap, eur_24h_vol)
+const (
+	marketCapSuffix = "_market_cap"
+	volume24hSuffix = "_24h_vol"
+	change24hSuffix = "_24h_change"
+)
+
+// UnmarshalJSON разбирает ответ вида {"usd": 45000.5, "eur": 41000.1}.
+// Поля usd_market_cap, usd_24h_vol и usd_24h_change попадают в Market.
+// Цены разбираются из текста числа без промежуточного float64.
+func (q *PriceQuote) UnmarshalJSON(data []byte) error {
+	var fields map[string]*json.Number
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*q = PriceQuote{}
+	for key, value := range fields {
+		// CoinGecko возвращает null для показателей, которых у валюты нет
+		if value == nil {
+			continue
+		}
+		key = strings.ToLower(key)
+		var err error
+		switch {
+		case strings.HasSuffix(key, marketCapSuffix):
+			quote := strings.TrimSuffix(key, marketCapSuffix)
+			market := q.Market[quote]
+			market.MarketCap, err = value.Float64()
+			q.SetMarket(quote, market)
+		case strings.HasSuffix(key, volume24hSuffix):
+			quote := strings.TrimSuffix(key, volume24hSuffix)
+			market := q.Market[quote]
+			market.Volume24h, err = value.Float64()
+			q.SetMarket(quote, market)
+		case strings.HasSuffix(key, change24hSuffix):
+			quote := strings.TrimSuffix(key, change24hSuffix)
+			market := q.Market[quote]
+			market.Change24h, err = value.Float64()
+			q.SetMarket(quote, market)
+		default:
+			var price Decimal
+			price, err = ParseDecimal(value.String())
+			q.SetPrice(key, price)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
 }
 
 type CurrencyRateView struct {
-	NameCurrency string    `json:"name_currency"`
-	Price        float64   `json:"price"`
-	RecordedAt   time.Time `json:"recorded_at"`
-	CurrencyID   int       `json:"currency_id"`
+	NameCurrency string        `json:"name_currency"`
+	Price        Decimal       `json:"price"`
+	Quote        string        `json:"quote"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+	CurrencyID   int           `json:"currency_id"`
+	Market       *MarketData   `json:"market,omitempty"`
+	MaxAge       time.Duration `json:"-"`
+}
+
+// RateSummary последний курс валюты вместе с её описанием и статистикой:
+// минимум и максимум за календарный день, изменение в процентах за час,
+// с начала дня и за 24 часа. Изменение равно нулю, если курса на начало
+// периода нет.
+type RateSummary struct {
+	CurrencyID   int           `json:"currency_id"`
+	NameCurrency string        `json:"name_currency"`
+	Symbol       string        `json:"symbol"`
+	DisplayName  string        `json:"display_name"`
+	Quote        string        `json:"quote"`
+	Price        Decimal       `json:"price"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+	DailyMin     Decimal       `json:"daily_min"`
+	DailyMax     Decimal       `json:"daily_max"`
+	HourlyChange float64       `json:"hourly_change"`
+	DayChange    float64       `json:"day_change"`
+	Change24h    float64       `json:"change_24h"`
+	Market       *MarketData   `json:"market,omitempty"`
+	MaxAge       time.Duration `json:"-"`
+}
+
+// CacheStats счётчики кэша курсов. HitRatio - доля обращений,
+// обслуженных из кэша, ноль до первого обращения.
+type CacheStats struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	Invalidations uint64  `json:"invalidations"`
+	HitRatio      float64 `json:"hit_ratio"`
+}
+
+// CurrencyFreshness время последнего курса отслеживаемой валюты.
+// LastRecordedAt равен nil, если курсов ещё не было.
+type CurrencyFreshness struct {
+	NameCurrency   string
+	MaxAge         time.Duration
+	LastRecordedAt *time.Time
+}
+
+// Freshness возвращает возраст курса и признак устаревания.
+// Нулевой maxAge означает DefaultMaxAge.
+func Freshness(recordedAt time.Time, maxAge time.Duration, now time.Time) (age time.Duration, stale bool) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	age = now.Sub(recordedAt)
+	if age < 0 {
+		age = 0
+	}
+	return age, age > maxAge
+}
+
+// CalendarDay возвращает начало и конец календарного дня, в который попадает now
+// в часовом поясе loc. Длина дня учитывает переход на летнее время.
+func CalendarDay(now time.Time, loc *time.Location) (start, end time.Time) {
+	local := now.In(loc)
+	start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// LoadTimezone возвращает часовой пояс IANA (Europe/Moscow).
+// Пустое имя означает UTC, часовой пояс сервера (Local) не принимается.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %s", name)
+	}
+	return time.LoadLocation(name)
 }
 
 type UserSettings struct {
-    UserID     int64      `json:"user_id"`
-    Interval   int        `json:"interval"`
-    LastSent   time.Time  `json:"last_sent"`
-    Currencies []Currency `json:"currencies"`
-}
\ No newline at end of file
+	UserID     int64      `json:"user_id"`
+	Interval   int        `json:"interval"`
+	LastSent   time.Time  `json:"last_sent"`
+	Currencies []Currency `json:"currencies"`
+	// Timezone часовой пояс IANA пользователя, пустой - UTC
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Статусы цикла загрузки курсов
+const (
+	FetchRunRunning = "running" // цикл ещё идёт или процесс упал до завершения
+	FetchRunSuccess = "success" // сохранены курсы всех валют
+	FetchRunPartial = "partial" // часть валют пропущена
+	FetchRunFailed  = "failed"  // не сохранено ни одного курса
+	FetchRunSkipped = "skipped" // нет отслеживаемых валют
+)
+
+// FetchRun запись о цикле загрузки курсов воркером
+type FetchRun struct {
+	ID             int        `json:"id"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	Provider       string     `json:"provider"`
+	CoinsRequested int        `json:"coins_requested"`
+	RatesSaved     int        `json:"rates_saved"`
+	CoinsSkipped   int        `json:"coins_skipped"`
+	Status         string     `json:"status"`
+	Error          string     `json:"error,omitempty"`
+}
@@ -1,168 +1,292 @@
 package models
 
 import (
-    "encoding/json"
-    "testing"
-    "time"
+	"encoding/json"
+	"testing"
+	"time"
 )
 
 func TestCurrency_JSON(t *testing.T) {
-    currency := Currency{
-        ID:           1,
-        NameCurrency: "bitcoin",
-        DisplayName:  "Bitcoin",
-        Symbol:       "BTC",
-    }
-
-    data, err := json.Marshal(currency)
-    if err != nil {
-        t.Fatalf("Failed to marshal currency: %v", err)
-    }
-
-    var decoded Currency
-    if err := json.Unmarshal(data, &decoded); err != nil {
-        t.Fatalf("Failed to unmarshal currency: %v", err)
-    }
-
-    if decoded != currency {
-        t.Errorf("Decoded currency doesn't match original. Got %+v, want %+v", decoded, currency)
-    }
+	currency := Currency{
+		ID:           1,
+		NameCurrency: "bitcoin",
+		DisplayName:  "Bitcoin",
+		Symbol:       "BTC",
+	}
+
+	data, err := json.Marshal(currency)
+	if err != nil {
+		t.Fatalf("Failed to marshal currency: %v", err)
+	}
+
+	var decoded Currency
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal currency: %v", err)
+	}
+
+	if decoded != currency {
+		t.Errorf("Decoded currency doesn't match original. Got %+v, want %+v", decoded, currency)
+	}
 }
 
 func TestExchangeRate_JSON(t *testing.T) {
-    now := time.Now()
-    rate := ExchangeRate{
-        ID:         1,
-        CurrencyID: 1,
-        Price:      45000.50,
-        RecordedAt: now,
-    }
-
-    data, err := json.Marshal(rate)
-    if err != nil {
-        t.Fatalf("Failed to marshal exchange rate: %v", err)
-    }
-
-    var decoded ExchangeRate
-    if err := json.Unmarshal(data, &decoded); err != nil {
-        t.Fatalf("Failed to unmarshal exchange rate: %v", err)
-    }
-
-    // Сравниваем с точностью до микросекунд из-за JSON сериализации времени
-    if decoded.ID != rate.ID || decoded.CurrencyID != rate.CurrencyID || 
-    decoded.Price != rate.Price {
-        t.Errorf("Decoded rate doesn't match original. Got %+v, want %+v", decoded, rate)
-    }
+	now := time.Now()
+	rate := ExchangeRate{
+		ID:         1,
+		CurrencyID: 1,
+		Price:      MustParseDecimal("45000.50"),
+		RecordedAt: now,
+	}
+
+	data, err := json.Marshal(rate)
+	if err != nil {
+		t.Fatalf("Failed to marshal exchange rate: %v", err)
+	}
+
+	var decoded ExchangeRate
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal exchange rate: %v", err)
+	}
+
+	// Сравниваем с точностью до микросекунд из-за JSON сериализации времени
+	if decoded.ID != rate.ID || decoded.CurrencyID != rate.CurrencyID ||
+		!decoded.Price.Equal(rate.Price) {
+		t.Errorf("Decoded rate doesn't match original. Got %+v, want %+v", decoded, rate)
+	}
 }
 
 func TestCoinGeckoResponse_Parse(t *testing.T) {
-    jsonData := `{
+	jsonData := `{
         "bitcoin": {"usd": 45000.50},
         "ethereum": {"usd": 2500.75}
     }`
 
-    var response CoinGeckoResponse
-    if err := json.Unmarshal([]byte(jsonData), &response); err != nil {
-        t.Fatalf("Failed to parse CoinGecko response: %v", err)
-    }
+	var response CoinGeckoResponse
+	if err := json.Unmarshal([]byte(jsonData), &response); err != nil {
+		t.Fatalf("Failed to parse CoinGecko response: %v", err)
+	}
+
+	if len(response) != 2 {
+		t.Errorf("Expected 2 currencies, got %d", len(response))
+	}
+
+	if btc, ok := response["bitcoin"]; !ok || btc.USD.String() != "45000.5" {
+		t.Errorf("Bitcoin price incorrect. Got %+v", btc)
+	}
+
+	if eth, ok := response["ethereum"]; !ok || eth.USD.String() != "2500.75" {
+		t.Errorf("Ethereum price incorrect. Got %+v", eth)
+	}
+}
+
+func TestPriceQuote_MultipleQuotes(t *testing.T) {
+	jsonData := `{"bitcoin": {"usd": 45000.50, "eur": 41000.25, "btc": 1}}`
+
+	var response CoinGeckoResponse
+	if err := json.Unmarshal([]byte(jsonData), &response); err != nil {
+		t.Fatalf("Failed to parse CoinGecko response: %v", err)
+	}
+
+	btc := response["bitcoin"]
+	if btc.USD.String() != "45000.5" {
+		t.Errorf("USD price incorrect. Got %v", btc.USD)
+	}
+
+	testCases := []struct {
+		quote string
+		want  string
+		ok    bool
+	}{
+		{"usd", "45000.5", true},
+		{"EUR", "41000.25", true},
+		{"btc", "1", true},
+		{"rub", "0", false},
+	}
+
+	for _, tc := range testCases {
+		price, ok := btc.Price(tc.quote)
+		if ok != tc.ok || price.String() != tc.want {
+			t.Errorf("Price(%s) = %v, %v; want %v, %v", tc.quote, price, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestPriceQuote_MarketData(t *testing.T) {
+	jsonData := `{"bitcoin": {
+        "usd": 45000.50, "usd_market_cap": 880000000000, "usd_24h_vol": 25000000000, "usd_24h_change": -1.25,
+        "eur": 41000.25, "eur_market_cap": 810000000000, "eur_24h_vol": null, "eur_24h_change": null
+    }}`
+
+	var response CoinGeckoResponse
+	if err := json.Unmarshal([]byte(jsonData), &response); err != nil {
+		t.Fatalf("Failed to parse CoinGecko response: %v", err)
+	}
+
+	btc := response["bitcoin"]
+	if len(btc.Prices) != 2 {
+		t.Errorf("Market fields must not be parsed as quotes, got prices %v", btc.Prices)
+	}
 
-    if len(response) != 2 {
-        t.Errorf("Expected 2 currencies, got %d", len(response))
-    }
+	usd, ok := btc.MarketData("USD")
+	if !ok || usd != (MarketData{MarketCap: 880000000000, Volume24h: 25000000000, Change24h: -1.25}) {
+		t.Errorf("Unexpected USD market data: %+v, %v", usd, ok)
+	}
 
-    if btc, ok := response["bitcoin"]; !ok || btc.USD != 45000.50 {
-        t.Errorf("Bitcoin price incorrect. Got %+v", btc)
-    }
+	eur, ok := btc.MarketData("eur")
+	if !ok || eur.MarketCap != 810000000000 || eur.Volume24h != 0 {
+		t.Errorf("Unexpected EUR market data: %+v, %v", eur, ok)
+	}
 
-    if eth, ok := response["ethereum"]; !ok || eth.USD != 2500.75 {
-        t.Errorf("Ethereum price incorrect. Got %+v", eth)
-    }
+	if _, ok := btc.MarketData("rub"); ok {
+		t.Error("Expected no RUB market data")
+	}
 }
 
 func TestCurrencyRateView_JSON(t *testing.T) {
-    now := time.Now()
-    rateView := CurrencyRateView{
-        NameCurrency: "bitcoin",
-        Price:        45000.50,
-        RecordedAt:   now,
-    }
-
-    data, err := json.Marshal(rateView)
-    if err != nil {
-        t.Fatalf("Failed to marshal currency rate view: %v", err)
-    }
-
-    var decoded CurrencyRateView
-    if err := json.Unmarshal(data, &decoded); err != nil {
-        t.Fatalf("Failed to unmarshal currency rate view: %v", err)
-    }
-
-    if decoded.NameCurrency != rateView.NameCurrency || decoded.Price != rateView.Price {
-        t.Errorf("Decoded rate view doesn't match original. Got %+v, want %+v", decoded, rateView)
-    }
+	now := time.Now()
+	rateView := CurrencyRateView{
+		NameCurrency: "bitcoin",
+		Price:        MustParseDecimal("45000.50"),
+		RecordedAt:   now,
+	}
+
+	data, err := json.Marshal(rateView)
+	if err != nil {
+		t.Fatalf("Failed to marshal currency rate view: %v", err)
+	}
+
+	var decoded CurrencyRateView
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal currency rate view: %v", err)
+	}
+
+	if decoded.NameCurrency != rateView.NameCurrency || !decoded.Price.Equal(rateView.Price) {
+		t.Errorf("Decoded rate view doesn't match original. Got %+v, want %+v", decoded, rateView)
+	}
 }
 
 func TestUserSettings_JSON(t *testing.T) {
-    userSettings := UserSettings{
-        UserID:   123456,
-        Interval: 10,
-        LastSent: time.Now(),
-        Currencies: []Currency{
-            {ID: 1, NameCurrency: "bitcoin", DisplayName: "Bitcoin", Symbol: "BTC"},
-            {ID: 2, NameCurrency: "ethereum", DisplayName: "Ethereum", Symbol: "ETH"},
-        },
-    }
-
-    data, err := json.Marshal(userSettings)
-    if err != nil {
-        t.Fatalf("Failed to marshal user settings: %v", err)
-    }
-
-    var decoded UserSettings
-    if err := json.Unmarshal(data, &decoded); err != nil {
-        t.Fatalf("Failed to unmarshal user settings: %v", err)
-    }
-
-    if decoded.UserID != userSettings.UserID || decoded.Interval != userSettings.Interval {
-        t.Errorf("Decoded user settings don't match original")
-    }
-
-    if len(decoded.Currencies) != len(userSettings.Currencies) {
-        t.Errorf("Currency count mismatch. Got %d, want %d", 
-                len(decoded.Currencies), len(userSettings.Currencies))
-    }
+	userSettings := UserSettings{
+		UserID:   123456,
+		Interval: 10,
+		LastSent: time.Now(),
+		Currencies: []Currency{
+			{ID: 1, NameCurrency: "bitcoin", DisplayName: "Bitcoin", Symbol: "BTC"},
+			{ID: 2, NameCurrency: "ethereum", DisplayName: "Ethereum", Symbol: "ETH"},
+		},
+	}
+
+	data, err := json.Marshal(userSettings)
+	if err != nil {
+		t.Fatalf("Failed to marshal user settings: %v", err)
+	}
+
+	var decoded UserSettings
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal user settings: %v", err)
+	}
+
+	if decoded.UserID != userSettings.UserID || decoded.Interval != userSettings.Interval {
+		t.Errorf("Decoded user settings don't match original")
+	}
+
+	if len(decoded.Currencies) != len(userSettings.Currencies) {
+		t.Errorf("Currency count mismatch. Got %d, want %d",
+			len(decoded.Currencies), len(userSettings.Currencies))
+	}
 }
 
 // Бенчмарк тесты
 func BenchmarkCurrency_Marshal(b *testing.B) {
-    currency := Currency{
-        ID:           1,
-        NameCurrency: "bitcoin",
-        DisplayName:  "Bitcoin",
-        Symbol:       "BTC",
-    }
-
-    b.ResetTimer()
-    for i := 0; i < b.N; i++ {
-        _, err := json.Marshal(currency)
-        if err != nil {
-            b.Fatalf("Marshal failed: %v", err)
-        }
-    }
+	currency := Currency{
+		ID:           1,
+		NameCurrency: "bitcoin",
+		DisplayName:  "Bitcoin",
+		Symbol:       "BTC",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := json.Marshal(currency)
+		if err != nil {
+			b.Fatalf("Marshal failed: %v", err)
+		}
+	}
 }
 
 func BenchmarkCoinGeckoResponse_Unmarshal(b *testing.B) {
-    jsonData := []byte(`{
+	jsonData := []byte(`{
         "bitcoin": {"usd": 45000.50},
         "ethereum": {"usd": 2500.75},
         "tether": {"usd": 1.00}
     }`)
 
-    b.ResetTimer()
-    for i := 0; i < b.N; i++ {
-        var response CoinGeckoResponse
-        if err := json.Unmarshal(jsonData, &response); err != nil {
-            b.Fatalf("Unmarshal failed: %v", err)
-        }
-    }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var response CoinGeckoResponse
+		if err := json.Unmarshal(jsonData, &response); err != nil {
+			b.Fatalf("Unmarshal failed: %v", err)
+		}
+	}
+}
+
+func TestFreshness(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		recordedAt time.Time
+		maxAge     time.Duration
+		wantAge    time.Duration
+		wantStale  bool
+	}{
+		{"fresh with default", now.Add(-10 * time.Minute), 0, 10 * time.Minute, false},
+		{"stale with default", now.Add(-16 * time.Minute), 0, 16 * time.Minute, true},
+		{"fresh with custom max age", now.Add(-time.Hour), 2 * time.Hour, time.Hour, false},
+		{"stale with custom max age", now.Add(-6 * time.Minute), 5 * time.Minute, 6 * time.Minute, true},
+		{"recorded in the future", now.Add(time.Minute), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		age, stale := Freshness(tt.recordedAt, tt.maxAge, now)
+		if age != tt.wantAge || stale != tt.wantStale {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", tt.name, age, stale, tt.wantAge, tt.wantStale)
+		}
+	}
+}
+
+func TestCalendarDay(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+
+	// 23:30 UTC 30 марта - уже 31 марта в Берлине
+	now := time.Date(2025, 3, 30, 23, 30, 0, 0, time.UTC)
+	start, end := CalendarDay(now, berlin)
+
+	if want := time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Expected day start %v, got %v", want, start.UTC())
+	}
+	if want := time.Date(2025, 3, 31, 22, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("Expected day end %v, got %v", want, end.UTC())
+	}
+
+	// В день перехода на летнее время в сутках 23 часа
+	start, end = CalendarDay(time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC), berlin)
+	if got := end.Sub(start); got != 23*time.Hour {
+		t.Errorf("Expected 23h day, got %v", got)
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	if loc, err := LoadTimezone(""); err != nil || loc != time.UTC {
+		t.Errorf("Expected UTC for empty name, got %v, %v", loc, err)
+	}
+	if _, err := LoadTimezone("Local"); err == nil {
+		t.Error("Expected error for server local time zone")
+	}
+	if _, err := LoadTimezone("Mars/Olympus"); err == nil {
+		t.Error("Expected error for unknown time zone")
+	}
 }
@@ -0,0 +1,289 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxDecimalExponent ограничивает показатель степени в записи 1e-5,
+// чтобы строка вида 1e999999999 не приводила к огромному числу
+const maxDecimalExponent = 1000
+
+// Decimal точное десятичное число для цен: unscaled * 10^-scale.
+// Нулевое значение равно 0. Значения неизменяемы, операции возвращают новое число.
+// В JSON кодируется строкой ("0.00001234"), чтобы клиенты не теряли точность.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// ParseDecimal разбирает число вида 45000.5, -0.000012 или 1.2e-05
+func ParseDecimal(s string) (Decimal, error) {
+	text := s
+	exp := 0
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		e, err := strconv.Atoi(text[i+1:])
+		if err != nil || e > maxDecimalExponent || e < -maxDecimalExponent {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		exp, text = e, text[:i]
+	}
+
+	neg := false
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		neg, text = text[0] == '-', text[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(text, ".")
+	if intPart+fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	unscaled, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	if neg {
+		unscaled.Neg(unscaled)
+	}
+	return newDecimal(unscaled, len(fracPart)-exp), nil
+}
+
+// MustParseDecimal как ParseDecimal, но паникует при ошибке.
+// Используется для констант в коде и тестах.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecimalFromInt возвращает целое число v
+func DecimalFromInt(v int64) Decimal {
+	return newDecimal(big.NewInt(v), 0)
+}
+
+// DecimalFromFloat возвращает кратчайшее десятичное представление f:
+// 0.1 превращается в 0.1, а не в 0.1000000000000000055511151231257827
+func DecimalFromFloat(f float64) (Decimal, error) {
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newDecimal приводит число к каноническому виду: без отрицательного
+// масштаба и без нулей в конце дробной части, поэтому равные числа
+// имеют одинаковое представление
+func newDecimal(unscaled *big.Int, scale int) Decimal {
+	if unscaled.Sign() == 0 {
+		return Decimal{}
+	}
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+
+	ten := big.NewInt(10)
+	quo, rem := new(big.Int), new(big.Int)
+	for scale > 0 {
+		quo.QuoRem(unscaled, ten, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		unscaled.Set(quo)
+		scale--
+	}
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// int возвращает копию unscaled, для нулевого значения - 0
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// rescale возвращает unscaled числа d в масштабе scale >= d.scale
+func (d Decimal) rescale(scale int) *big.Int {
+	v := d.int()
+	if scale > d.scale {
+		v.Mul(v, pow10(scale-d.scale))
+	}
+	return v
+}
+
+// Add возвращает d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return newDecimal(new(big.Int).Add(d.rescale(scale), other.rescale(scale)), scale)
+}
+
+// Sub возвращает d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return newDecimal(new(big.Int).Sub(d.rescale(scale), other.rescale(scale)), scale)
+}
+
+// Mul возвращает d * other
+func (d Decimal) Mul(other Decimal) Decimal {
+	return newDecimal(new(big.Int).Mul(d.int(), other.int()), d.scale+other.scale)
+}
+
+// Cmp сравнивает числа: -1 если d < other, 0 если равны, +1 если d > other
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Equal сообщает, равны ли числа
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Sign возвращает -1, 0 или +1 в зависимости от знака числа
+func (d Decimal) Sign() int {
+	if d.unscaled == nil {
+		return 0
+	}
+	return d.unscaled.Sign()
+}
+
+// IsZero сообщает, равно ли число нулю. Поля с тегом omitzero
+// не попадают в JSON, если цена нулевая.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 возвращает ближайшее число float64. Подходит для процентов
+// и сравнений, но не для хранения цен.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String возвращает число без экспоненты и без лишних нулей: 45000.5, 0.00001234
+func (d Decimal) String() string {
+	return formatDecimal(d.int(), d.scale)
+}
+
+// StringFixed возвращает число ровно с places знаками после запятой,
+// округляя половину от нуля: 45000.505 -> "45000.51" при places = 2
+func (d Decimal) StringFixed(places int) string {
+	v := d.int()
+	if d.scale <= places {
+		return formatDecimal(v.Mul(v, pow10(places-d.scale)), places)
+	}
+
+	divisor := pow10(d.scale - places)
+	neg := v.Sign() < 0
+	quo, rem := new(big.Int).QuoRem(v.Abs(v), divisor, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(divisor) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if neg {
+		quo.Neg(quo)
+	}
+	return formatDecimal(quo, places)
+}
+
+// formatDecimal записывает unscaled * 10^-scale, сохраняя scale знаков после запятой
+func formatDecimal(unscaled *big.Int, scale int) string {
+	digits := new(big.Int).Abs(unscaled).String()
+	sign := ""
+	if unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if scale <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	point := len(digits) - scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// MarshalJSON кодирует число строкой
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON принимает и строку "45000.5", и число 45000.5:
+// внешние API отдают цены числами, а собственный API - строками
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	parsed, err := ParseDecimal(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Scan читает NUMERIC из базы. lib/pq отдаёт NUMERIC текстом, поэтому
+// значение не проходит через float64.
+func (d *Decimal) Scan(src interface{}) error {
+	var (
+		parsed Decimal
+		err    error
+	)
+	switch v := src.(type) {
+	case []byte:
+		parsed, err = ParseDecimal(string(v))
+	case string:
+		parsed, err = ParseDecimal(v)
+	case int64:
+		parsed = DecimalFromInt(v)
+	case float64:
+		parsed, err = DecimalFromFloat(v)
+	case nil:
+		return fmt.Errorf("cannot scan NULL into Decimal")
+	default:
+		return fmt.Errorf("cannot scan %T into Decimal", src)
+	}
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value передаёт число в базу строкой, Postgres приводит её к NUMERIC без потерь
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// NullDecimal Decimal, который может быть NULL, по аналогии с sql.NullFloat64
+type NullDecimal struct {
+	Decimal Decimal
+	Valid   bool
+}
+
+// Scan реализует sql.Scanner
+func (n *NullDecimal) Scan(src interface{}) error {
+	if src == nil {
+		n.Decimal, n.Valid = Decimal{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Decimal.Scan(src)
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{"45000.50", "45000.5"},
+		{"0.000012340", "0.00001234"},
+		{"1.234e-05", "0.00001234"},
+		{"1.5E3", "1500"},
+		{"-0.10", "-0.1"},
+		{"+7", "7"},
+		{".5", "0.5"},
+		{"0.000", "0"},
+		{"123456789012345678901234.123456789012345678", "123456789012345678901234.123456789012345678"},
+	}
+
+	for _, tc := range testCases {
+		d, err := ParseDecimal(tc.input)
+		if err != nil {
+			t.Errorf("ParseDecimal(%q) error: %v", tc.input, err)
+			continue
+		}
+		if got := d.String(); got != tc.want {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", tc.input, got, tc.want)
+		}
+	}
+
+	for _, input := range []string{"", "-", ".", "abc", "1.2.3", "1e", "NaN", "Inf", "1e100000"} {
+		if _, err := ParseDecimal(input); err == nil {
+			t.Errorf("ParseDecimal(%q) expected error", input)
+		}
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	a := MustParseDecimal("0.1")
+	b := MustParseDecimal("0.2")
+
+	// Сумма, которая в float64 даёт 0.30000000000000004
+	if got := a.Add(b).String(); got != "0.3" {
+		t.Errorf("0.1 + 0.2 = %s, want 0.3", got)
+	}
+	if got := a.Sub(b).String(); got != "-0.1" {
+		t.Errorf("0.1 - 0.2 = %s, want -0.1", got)
+	}
+	if got := MustParseDecimal("0.00001234").Mul(DecimalFromInt(1000000)).String(); got != "12.34" {
+		t.Errorf("Mul = %s, want 12.34", got)
+	}
+	if a.Cmp(b) != -1 || b.Cmp(a) != 1 || !a.Equal(MustParseDecimal("0.100")) {
+		t.Error("Unexpected comparison result")
+	}
+
+	var zero Decimal
+	if !zero.IsZero() || zero.String() != "0" || zero.Add(a).String() != "0.1" {
+		t.Errorf("Zero value must behave as 0, got %s", zero)
+	}
+}
+
+func TestDecimal_StringFixed(t *testing.T) {
+	testCases := []struct {
+		input  string
+		places int
+		want   string
+	}{
+		{"45000.505", 2, "45000.51"},
+		{"45000.504", 2, "45000.50"},
+		{"-1.005", 2, "-1.01"},
+		{"12", 2, "12.00"},
+		{"0.00001234", 8, "0.00001234"},
+		{"0.000000001", 8, "0.00000000"},
+	}
+
+	for _, tc := range testCases {
+		if got := MustParseDecimal(tc.input).StringFixed(tc.places); got != tc.want {
+			t.Errorf("StringFixed(%s, %d) = %s, want %s", tc.input, tc.places, got, tc.want)
+		}
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Decimal `json:"price"`
+	}{MustParseDecimal("0.00001234")})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if string(data) != `{"price":"0.00001234"}` {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+
+	// Принимаются и строки, и числа
+	for _, input := range []string{`"0.00001234"`, `0.00001234`, `1.234e-05`} {
+		var d Decimal
+		if err := json.Unmarshal([]byte(input), &d); err != nil {
+			t.Errorf("Unmarshal(%s) error: %v", input, err)
+			continue
+		}
+		if d.String() != "0.00001234" {
+			t.Errorf("Unmarshal(%s) = %s", input, d)
+		}
+	}
+}
+
+func TestDecimal_Scan(t *testing.T) {
+	var d Decimal
+	if err := d.Scan([]byte("0.000012340000000000")); err != nil || d.String() != "0.00001234" {
+		t.Errorf("Scan([]byte) = %s, %v", d, err)
+	}
+	if err := d.Scan(int64(5)); err != nil || d.String() != "5" {
+		t.Errorf("Scan(int64) = %s, %v", d, err)
+	}
+	if err := d.Scan(nil); err == nil {
+		t.Error("Scan(nil) expected error")
+	}
+
+	var n NullDecimal
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Errorf("NullDecimal.Scan(nil) = %+v, %v", n, err)
+	}
+	if err := n.Scan("1.5"); err != nil || !n.Valid || n.Decimal.String() != "1.5" {
+		t.Errorf("NullDecimal.Scan(1.5) = %+v, %v", n, err)
+	}
+}
+
+func TestPriceQuote_TinyPrice(t *testing.T) {
+	// CoinGecko отдаёт цены мельче цента в экспоненциальной записи
+	var response CoinGeckoResponse
+	if err := json.Unmarshal([]byte(`{"shiba-inu": {"usd": 1.234e-05, "btc": 1.9e-10}}`), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	shib := response["shiba-inu"]
+	if usd, ok := shib.Price("usd"); !ok || usd.String() != "0.00001234" {
+		t.Errorf("USD price = %s, %v", usd, ok)
+	}
+	if btc, ok := shib.Price("btc"); !ok || btc.String() != "0.00000000019" {
+		t.Errorf("BTC price = %s, %v", btc, ok)
+	}
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RatesChangedChannel канал LISTEN/NOTIFY, в который база сообщает о новых
+// курсах и изменении валют (см. миграцию 0013_rates_changed_notify)
+const RatesChangedChannel = "rates_changed"
+
+// DefaultCacheTTL срок жизни записи кэша, если уведомление о новых курсах
+// потерялось, например пока соединение слушателя переподключалось
+const DefaultCacheTTL = 5 * time.Minute
+
+// CachedRepository Repository с кэшем сводок курсов в памяти процесса.
+// Курсы меняются раз в цикл воркера, поэтому запросы между циклами
+// обслуживаются без обращения к базе. Кэш сбрасывается по уведомлению
+// из канала RatesChangedChannel (см. Watch), остальные методы
+// обращаются к базе напрямую.
+type CachedRepository struct {
+	*Repository
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// generation растёт при каждом сбросе, чтобы не сохранить в кэш
+	// результат запроса, начатого до сброса
+	generation uint64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+type cacheEntry struct {
+	summaries []models.RateSummary
+	expires   time.Time
+}
+
+// NewCachedRepository оборачивает repo кэшем со сроком жизни записей ttl.
+// Ноль означает DefaultCacheTTL.
+func NewCachedRepository(repo *Repository, ttl time.Duration) *CachedRepository {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedRepository{
+		Repository: repo,
+		ttl:        ttl,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// GetRateSummaries возвращает сводки из кэша или читает их из базы.
+// Запись живёт не дольше ttl и не переживает конец календарного дня в loc,
+// после которого дневная статистика начинается заново.
+func (c *CachedRepository) GetRateSummaries(ctx context.Context, quote string, loc *time.Location, currencyIDs ...int) ([]models.RateSummary, error) {
+	key := summaryKey(quote, loc, currencyIDs)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		c.hits.Add(1)
+		return append([]models.RateSummary(nil), entry.summaries...), nil
+	}
+	c.misses.Add(1)
+
+	summaries, err := c.Repository.GetRateSummaries(ctx, quote, loc, currencyIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := now.Add(c.ttl)
+	if _, dayEnd := models.CalendarDay(now, loc); dayEnd.Before(expires) {
+		expires = dayEnd
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.entries[key] = cacheEntry{summaries: summaries, expires: expires}
+	}
+	c.mu.Unlock()
+
+	return append([]models.RateSummary(nil), summaries...), nil
+}
+
+// summaryKey ключ кэша: котировка, часовой пояс и отсортированный список валют
+func summaryKey(quote string, loc *time.Location, currencyIDs []int) string {
+	ids := append([]int(nil), currencyIDs...)
+	sort.Ints(ids)
+
+	var key strings.Builder
+	key.WriteString(quoteOrDefault(quote))
+	key.WriteString("|")
+	key.WriteString(loc.String())
+	for _, id := range ids {
+		key.WriteString("|")
+		key.WriteString(strconv.Itoa(id))
+	}
+	return key.String()
+}
+
+// Invalidate сбрасывает все записи кэша
+func (c *CachedRepository) Invalidate() {
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.generation++
+	c.mu.Unlock()
+	c.invalidations.Add(1)
+}
+
+// CacheStats возвращает счётчики обращений к кэшу с момента запуска
+func (c *CachedRepository) CacheStats() models.CacheStats {
+	stats := models.CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// Watch сбрасывает кэш при каждом уведомлении до отмены ctx или закрытия
+// канала. pq.Listener присылает nil после переподключения: уведомления
+// за время разрыва потеряны, поэтому кэш тоже сбрасывается.
+func (c *CachedRepository) Watch(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-notifications:
+			if !ok {
+				return
+			}
+			c.Invalidate()
+		}
+	}
+}
+
+// ListenRatesChanged подписывается на RatesChangedChannel отдельным
+// соединением к базе dsn. Уведомления приходят в listener.Notify.
+func ListenRatesChanged(dsn string) (*pq.Listener, error) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("rates listener: %v", err)
+		}
+	})
+	if err := listener.Listen(RatesChangedChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listen %s: %w", RatesChangedChannel, err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// expectSummaries ожидает один запрос сводок и возвращает одну валюту
+func expectSummaries(mock sqlmock.Sqlmock, price string) {
+	mock.ExpectQuery(`SELECT c\.id, c\.name_currency`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name_currency", "symbol", "display_name", "max_age_seconds",
+			"price", "recorded_at", "market_cap", "volume_24h", "change_24h",
+			"min_price", "max_price", "open_price", "price", "price"}).
+			AddRow(1, "bitcoin", "BTC", "Bitcoin", nil, []byte(price), time.Now(), nil, nil, nil,
+				nil, nil, nil, nil, nil))
+}
+
+func TestCachedRepository_GetRateSummaries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	cache := NewCachedRepository(NewRepository(db), time.Minute)
+	ctx := context.Background()
+
+	// Первое обращение идёт в базу, повторное - из кэша
+	expectSummaries(mock, "45000")
+	for i := 0; i < 3; i++ {
+		summaries, err := cache.GetRateSummaries(ctx, "usd", time.UTC)
+		if err != nil {
+			t.Fatalf("GetRateSummaries failed: %v", err)
+		}
+		if len(summaries) != 1 || summaries[0].Price.String() != "45000" {
+			t.Fatalf("Unexpected summaries: %+v", summaries)
+		}
+	}
+
+	// Другой часовой пояс - другая дневная статистика и отдельная запись
+	expectSummaries(mock, "45000")
+	if _, err := cache.GetRateSummaries(ctx, "", time.FixedZone("MSK", 3*60*60)); err != nil {
+		t.Fatalf("GetRateSummaries failed: %v", err)
+	}
+
+	// После сброса курс читается заново
+	cache.Invalidate()
+	expectSummaries(mock, "46000")
+	summaries, err := cache.GetRateSummaries(ctx, "usd", time.UTC)
+	if err != nil {
+		t.Fatalf("GetRateSummaries failed: %v", err)
+	}
+	if summaries[0].Price.String() != "46000" {
+		t.Errorf("Expected fresh price after invalidation, got %s", summaries[0].Price)
+	}
+
+	stats := cache.CacheStats()
+	if stats.Hits != 2 || stats.Misses != 3 || stats.Invalidations != 1 || stats.HitRatio != 0.4 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCachedRepository_Expiry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	cache := NewCachedRepository(NewRepository(db), time.Millisecond)
+	ctx := context.Background()
+
+	// Уведомление потерялось: запись всё равно устаревает через ttl
+	expectSummaries(mock, "45000")
+	expectSummaries(mock, "45100")
+	if _, err := cache.GetRateSummaries(ctx, "usd", time.UTC, 1); err != nil {
+		t.Fatalf("GetRateSummaries failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	summaries, err := cache.GetRateSummaries(ctx, "usd", time.UTC, 1)
+	if err != nil {
+		t.Fatalf("GetRateSummaries failed: %v", err)
+	}
+	if summaries[0].Price.String() != "45100" {
+		t.Errorf("Expected expired entry to be reloaded, got %s", summaries[0].Price)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCachedRepository_Watch(t *testing.T) {
+	cache := NewCachedRepository(NewRepository(nil), time.Minute)
+	notifications := make(chan *pq.Notification)
+	done := make(chan struct{})
+	go func() {
+		cache.Watch(context.Background(), notifications)
+		close(done)
+	}()
+
+	// Уведомление о новых курсах и nil после переподключения слушателя
+	notifications <- &pq.Notification{Channel: RatesChangedChannel, Extra: "exchange_rate"}
+	notifications <- nil
+	close(notifications)
+	<-done
+
+	if got := cache.CacheStats().Invalidations; got != 2 {
+		t.Errorf("Expected 2 invalidations, got %d", got)
+	}
+}
+
+func TestSummaryKey(t *testing.T) {
+	if summaryKey("", time.UTC, []int{3, 1}) != summaryKey("usd", time.UTC, []int{1, 3}) {
+		t.Error("Key must not depend on currency order and default quote")
+	}
+	if summaryKey("usd", time.UTC, nil) == summaryKey("usd", time.UTC, []int{1}) {
+		t.Error("All currencies and selected currencies must have different keys")
+	}
+}
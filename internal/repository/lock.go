@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AdvisoryLock сессионная advisory-блокировка PostgreSQL. Блокировка
+// принадлежит выделенному соединению и снимается сервером при его разрыве,
+// поэтому упавший процесс не удерживает её.
+type AdvisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// TryAdvisoryLock пытается взять блокировку key без ожидания.
+// Возвращает nil без ошибки, если блокировку держит другой процесс.
+func (r *Repository) TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// Held проверяет, что соединение живо и блокировка всё ещё принадлежит ему.
+// После перезапуска PostgreSQL или разрыва соединения возвращает false.
+func (l *AdvisoryLock) Held(ctx context.Context) bool {
+	var held bool
+	err := l.conn.QueryRowContext(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM pg_locks
+            WHERE locktype = 'advisory' AND granted AND objsubid = 1
+            AND pid = pg_backend_pid()
+            AND ((classid::bigint << 32) | objid::bigint) = $1
+        )`, l.key).Scan(&held)
+	return err == nil && held
+}
+
+// Release снимает блокировку и возвращает соединение в пул
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
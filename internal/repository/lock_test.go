@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepository_TryAdvisoryLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+
+	lock, err := repo.TryAdvisoryLock(ctx, 42)
+	if err != nil {
+		t.Fatalf("TryAdvisoryLock failed: %v", err)
+	}
+	if lock == nil {
+		t.Fatal("Expected lock to be acquired")
+	}
+
+	mock.ExpectQuery(`SELECT EXISTS \(\s*SELECT 1 FROM pg_locks`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	if !lock.Held(ctx) {
+		t.Error("Expected lock to be held")
+	}
+
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRepository_TryAdvisoryLock_Busy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Блокировку держит другая реплика
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+	lock, err := repo.TryAdvisoryLock(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("TryAdvisoryLock failed: %v", err)
+	}
+	if lock != nil {
+		t.Error("Expected lock not to be acquired")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAdvisoryLock_Held_ConnectionLost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+
+	lock, err := repo.TryAdvisoryLock(ctx, 42)
+	if err != nil || lock == nil {
+		t.Fatalf("TryAdvisoryLock failed: %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WillReturnError(context.DeadlineExceeded)
+
+	if lock.Held(ctx) {
+		t.Error("Expected lock not to be held when the connection fails")
+	}
+}
@@ -0,0 +1,901 @@
+package repository
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepository реализация Store в памяти процесса для тестов
+// и демо-режима API. Повторяет поведение Repository, включая ошибки:
+// ErrNotFound и ErrConflict там же, где их возвращает Repository,
+// и sql.ErrNoRows в поиске валют и курсов. Агрегатов нет, история
+// и свечи всегда строятся по сырым курсам.
+type MemoryRepository struct {
+	mu sync.RWMutex
+
+	// currencies по возрастанию ID, удалённые валюты остаются в списке
+	currencies []memoryCurrency
+	// rates курсы каждой валюты в каждой котировке по возрастанию (recorded_at, id)
+	rates     map[rateKey][]models.ExchangeRate
+	budget    map[budgetKey]int
+	fetchRuns []models.FetchRun
+	users     map[int64]*memoryUser
+
+	lastCurrencyID int
+	lastRateID     int
+	lastFetchRunID int
+}
+
+type memoryCurrency struct {
+	models.Currency
+	deleted bool
+}
+
+type rateKey struct {
+	currencyID int
+	quote      string
+}
+
+type budgetKey struct {
+	provider    string
+	windowStart time.Time
+}
+
+// memoryUser строки Users, Settings и Currency_settings одного пользователя
+type memoryUser struct {
+	name     string
+	timezone string
+
+	// hasSettings есть ли строка Settings, interval 0 - автоотправка выключена
+	hasSettings bool
+	interval    int
+	lastSent    time.Time
+	active      map[int]bool
+}
+
+// NewMemoryRepository создаёт пустое хранилище в памяти
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		rates:  make(map[rateKey][]models.ExchangeRate),
+		budget: make(map[budgetKey]int),
+		users:  make(map[int64]*memoryUser),
+	}
+}
+
+// memoryNow текущее время с точностью TIMESTAMPTZ
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// SaveRate сохраняет курс с текущим временем
+func (m *MemoryRepository) SaveRate(ctx context.Context, rate models.ExchangeRate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rate.RecordedAt = memoryNow()
+	return m.insertRates([]models.ExchangeRate{rate})
+}
+
+// SaveRates сохраняет курсы одного цикла с общим временем recorded_at:
+// либо все курсы, либо ни одного
+func (m *MemoryRepository) SaveRates(ctx context.Context, rates []models.ExchangeRate) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recordedAt := memoryNow()
+	batch := make([]models.ExchangeRate, len(rates))
+	for i, rate := range rates {
+		rate.RecordedAt = recordedAt
+		batch[i] = rate
+	}
+	if err := m.insertRates(batch); err != nil {
+		return time.Time{}, err
+	}
+	return recordedAt, nil
+}
+
+// SaveHistoricalRates сохраняет исторические курсы, пропуская время,
+// рядом с которым (ближе historyDedupWindow) курс уже есть.
+// Возвращает число добавленных записей.
+func (m *MemoryRepository) SaveHistoricalRates(ctx context.Context, currencyID int, quote, source string, points []models.PricePoint) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quote = quoteOrDefault(quote)
+	var sources []string
+	if source != "" {
+		sources = []string{source}
+	}
+
+	var batch []models.ExchangeRate
+	seen := make(map[time.Time]bool, len(points))
+	for _, point := range points {
+		recordedAt := point.Time.UTC().Truncate(time.Microsecond)
+		if seen[recordedAt] || m.hasRateNear(currencyID, quote, recordedAt, historyDedupWindow) {
+			continue
+		}
+		seen[recordedAt] = true
+		batch = append(batch, models.ExchangeRate{
+			CurrencyID: currencyID,
+			Price:      point.Price,
+			Quote:      quote,
+			RecordedAt: recordedAt,
+			Sources:    sources,
+		})
+	}
+
+	if err := m.insertRates(batch); err != nil {
+		return 0, err
+	}
+	return int64(len(batch)), nil
+}
+
+// insertRates присваивает курсам ID и добавляет их в ряды своих валют.
+// Проверяет все валюты до изменений, как внешний ключ Exchange_rate.
+func (m *MemoryRepository) insertRates(rates []models.ExchangeRate) error {
+	for _, rate := range rates {
+		if m.currency(rate.CurrencyID) == nil {
+			return fmt.Errorf("currency %d does not exist", rate.CurrencyID)
+		}
+	}
+
+	for _, rate := range rates {
+		m.lastRateID++
+		rate.ID = m.lastRateID
+		rate.Quote = quoteOrDefault(rate.Quote)
+		rate.RecordedAt = rate.RecordedAt.UTC().Truncate(time.Microsecond)
+		rate.Sources = append([]string(nil), rate.Sources...)
+		rate.Market = copyMarket(rate.Market)
+
+		// ID нового курса наибольший, поэтому он встаёт после курсов с тем же временем
+		key := rateKey{currencyID: rate.CurrencyID, quote: rate.Quote}
+		series := m.rates[key]
+		i := sort.Search(len(series), func(i int) bool {
+			return series[i].RecordedAt.After(rate.RecordedAt)
+		})
+		series = append(series, models.ExchangeRate{})
+		copy(series[i+1:], series[i:])
+		series[i] = rate
+		m.rates[key] = series
+	}
+	return nil
+}
+
+// hasRateNear сообщает, есть ли в ряду курс ближе window к recordedAt
+func (m *MemoryRepository) hasRateNear(currencyID int, quote string, recordedAt time.Time, window time.Duration) bool {
+	series := m.rates[rateKey{currencyID: currencyID, quote: quote}]
+	i := sort.Search(len(series), func(i int) bool {
+		return series[i].RecordedAt.After(recordedAt.Add(-window))
+	})
+	return i < len(series) && series[i].RecordedAt.Before(recordedAt.Add(window))
+}
+
+// series возвращает курсы валюты в котировке по возрастанию времени
+func (m *MemoryRepository) series(currencyID int, quote string) []models.ExchangeRate {
+	return m.rates[rateKey{currencyID: currencyID, quote: quoteOrDefault(quote)}]
+}
+
+// between возвращает курсы ряда за период [from, to)
+func between(series []models.ExchangeRate, from, to time.Time) []models.ExchangeRate {
+	start := sort.Search(len(series), func(i int) bool {
+		return !series[i].RecordedAt.Before(from)
+	})
+	end := sort.Search(len(series), func(i int) bool {
+		return !series[i].RecordedAt.Before(to)
+	})
+	if end < start {
+		end = start
+	}
+	return series[start:end]
+}
+
+// priceAt возвращает последнюю цену ряда не позже at
+func priceAt(series []models.ExchangeRate, at time.Time) models.NullDecimal {
+	i := sort.Search(len(series), func(i int) bool {
+		return series[i].RecordedAt.After(at)
+	})
+	if i == 0 {
+		return models.NullDecimal{}
+	}
+	return models.NullDecimal{Decimal: series[i-1].Price, Valid: true}
+}
+
+func copyMarket(market *models.MarketData) *models.MarketData {
+	if market == nil {
+		return nil
+	}
+	copied := *market
+	return &copied
+}
+
+// ConsumeRequestBudget учитывает запрос к внешнему API в минутном окне.
+// Возвращает false, если лимит окна уже исчерпан.
+func (m *MemoryRepository) ConsumeRequestBudget(ctx context.Context, provider string, windowStart time.Time, limit int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	windowStart = windowStart.UTC().Truncate(time.Microsecond)
+	key := budgetKey{provider: provider, windowStart: windowStart}
+	requests, ok := m.budget[key]
+	if ok && requests >= limit {
+		return false, nil
+	}
+	m.budget[key] = requests + 1
+
+	// Первый запрос в новом окне удаляет устаревшие окна этого источника
+	if !ok {
+		expired := windowStart.Add(-time.Hour)
+		for other := range m.budget {
+			if other.provider == provider && other.windowStart.Before(expired) {
+				delete(m.budget, other)
+			}
+		}
+	}
+	return true, nil
+}
+
+// StartFetchRun записывает начало цикла загрузки курсов и возвращает его ID
+func (m *MemoryRepository) StartFetchRun(ctx context.Context, provider string, startedAt time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastFetchRunID++
+	m.fetchRuns = append(m.fetchRuns, models.FetchRun{
+		ID:        m.lastFetchRunID,
+		StartedAt: startedAt.UTC().Truncate(time.Microsecond),
+		Provider:  provider,
+		Status:    models.FetchRunRunning,
+	})
+	return m.lastFetchRunID, nil
+}
+
+// FinishFetchRun сохраняет итоги цикла загрузки курсов
+func (m *MemoryRepository) FinishFetchRun(ctx context.Context, run models.FetchRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	finishedAt := memoryNow()
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.UTC().Truncate(time.Microsecond)
+	}
+	for i := range m.fetchRuns {
+		stored := &m.fetchRuns[i]
+		if stored.ID != run.ID {
+			continue
+		}
+		stored.FinishedAt = &finishedAt
+		stored.CoinsRequested = run.CoinsRequested
+		stored.RatesSaved = run.RatesSaved
+		stored.CoinsSkipped = run.CoinsSkipped
+		stored.Status = run.Status
+		stored.Error = run.Error
+	}
+	return nil
+}
+
+// GetFetchRuns возвращает последние циклы загрузки курсов, новые первыми
+func (m *MemoryRepository) GetFetchRuns(ctx context.Context, limit int) ([]models.FetchRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runs := make([]models.FetchRun, len(m.fetchRuns))
+	copy(runs, m.fetchRuns)
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	if limit >= 0 && limit < len(runs) {
+		runs = runs[:limit]
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	for i := range runs {
+		if runs[i].FinishedAt != nil {
+			finishedAt := *runs[i].FinishedAt
+			runs[i].FinishedAt = &finishedAt
+		}
+	}
+	return runs, nil
+}
+
+// GetLastSuccessfulFetch возвращает время завершения последнего цикла,
+// в котором был сохранён хотя бы один курс. ErrNotFound, если таких циклов нет.
+func (m *MemoryRepository) GetLastSuccessfulFetch(ctx context.Context) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var last time.Time
+	for _, run := range m.fetchRuns {
+		if run.RatesSaved > 0 && run.FinishedAt != nil && run.FinishedAt.After(last) {
+			last = *run.FinishedAt
+		}
+	}
+	if last.IsZero() {
+		return time.Time{}, ErrNotFound
+	}
+	return last, nil
+}
+
+// currency возвращает валюту по ID, включая удалённые, или nil
+func (m *MemoryRepository) currency(id int) *memoryCurrency {
+	i := sort.Search(len(m.currencies), func(i int) bool {
+		return m.currencies[i].ID >= id
+	})
+	if i < len(m.currencies) && m.currencies[i].ID == id {
+		return &m.currencies[i]
+	}
+	return nil
+}
+
+// GetCurrencyID возвращает ID валюты по её имени без учёта регистра.
+// Действующая валюта важнее удалённой, среди равных - последняя добавленная.
+func (m *MemoryRepository) GetCurrencyID(ctx context.Context, name string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findCurrency(func(currency models.Currency) bool {
+		return strings.EqualFold(currency.NameCurrency, name)
+	})
+}
+
+// GetCurrencyIDBySymbol возвращает ID валюты по символу (BTC, ETH)
+func (m *MemoryRepository) GetCurrencyIDBySymbol(ctx context.Context, symbol string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findCurrency(func(currency models.Currency) bool {
+		return strings.EqualFold(currency.Symbol, symbol)
+	})
+}
+
+// findCurrency возвращает ID последней подходящей валюты, предпочитая неудалённые
+func (m *MemoryRepository) findCurrency(match func(models.Currency) bool) (int, error) {
+	found := 0
+	for i := len(m.currencies) - 1; i >= 0; i-- {
+		currency := m.currencies[i]
+		if !match(currency.Currency) {
+			continue
+		}
+		if !currency.deleted {
+			return currency.ID, nil
+		}
+		if found == 0 {
+			found = currency.ID
+		}
+	}
+	if found == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return found, nil
+}
+
+// GetCurrencySymbol возвращает символ валюты по её ID
+func (m *MemoryRepository) GetCurrencySymbol(ctx context.Context, currencyID int) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	currency := m.currency(currencyID)
+	if currency == nil {
+		return "", sql.ErrNoRows
+	}
+	return currency.Symbol, nil
+}
+
+// GetCurrencySymbolByID возвращает символ валюты по ID
+func (m *MemoryRepository) GetCurrencySymbolByID(ctx context.Context, currencyID int) (string, error) {
+	return m.GetCurrencySymbol(ctx, currencyID)
+}
+
+// GetCurrencyDisplayName возвращает отображаемое имя валюты
+func (m *MemoryRepository) GetCurrencyDisplayName(ctx context.Context, currencyID int) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	currency := m.currency(currencyID)
+	if currency == nil {
+		return "", sql.ErrNoRows
+	}
+	return currency.DisplayName, nil
+}
+
+// GetAllCurrencies возвращает все доступные валюты, кроме удалённых
+func (m *MemoryRepository) GetAllCurrencies(ctx context.Context) ([]models.Currency, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var currencies []models.Currency
+	for _, currency := range m.currencies {
+		if !currency.deleted {
+			currencies = append(currencies, currency.Currency)
+		}
+	}
+	return currencies, nil
+}
+
+// GetCurrencyByID возвращает валюту по ID, удалённые валюты не возвращаются
+func (m *MemoryRepository) GetCurrencyByID(ctx context.Context, id int) (models.Currency, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	currency := m.currency(id)
+	if currency == nil || currency.deleted {
+		return models.Currency{}, ErrNotFound
+	}
+	return currency.Currency, nil
+}
+
+// GetTrackedCurrencies возвращает валюты, курсы которых должен загружать воркер
+func (m *MemoryRepository) GetTrackedCurrencies(ctx context.Context) ([]models.Currency, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var currencies []models.Currency
+	for _, currency := range m.currencies {
+		if currency.IsTracked && !currency.deleted {
+			currencies = append(currencies, models.Currency{
+				ID:           currency.ID,
+				NameCurrency: currency.NameCurrency,
+				DisplayName:  currency.DisplayName,
+				Symbol:       currency.Symbol,
+				IsTracked:    true,
+			})
+		}
+	}
+	return currencies, nil
+}
+
+// GetTrackedFreshness возвращает время последнего курса в котировке quote
+// для каждой отслеживаемой валюты, включая валюты без курсов
+func (m *MemoryRepository) GetTrackedFreshness(ctx context.Context, quote string) ([]models.CurrencyFreshness, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.CurrencyFreshness
+	for _, currency := range m.currencies {
+		if !currency.IsTracked || currency.deleted {
+			continue
+		}
+		item := models.CurrencyFreshness{NameCurrency: currency.NameCurrency, MaxAge: currency.MaxAge}
+		if series := m.series(currency.ID, quote); len(series) > 0 {
+			recordedAt := series[len(series)-1].RecordedAt
+			item.LastRecordedAt = &recordedAt
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// CreateCurrency добавляет новую валюту и возвращает её с присвоенным ID.
+// Имя уникально среди неудалённых валют.
+func (m *MemoryRepository) CreateCurrency(ctx context.Context, currency models.Currency) (models.Currency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.nameTaken(currency.NameCurrency, 0) {
+		return models.Currency{}, ErrConflict
+	}
+
+	m.lastCurrencyID++
+	currency.ID = m.lastCurrencyID
+
+	// Возраст курса хранится в целых секундах, ноль и меньше - NULL
+	stored := currency
+	stored.MaxAge = maxAgeFromNull(maxAgeToNull(currency.MaxAge))
+	m.currencies = append(m.currencies, memoryCurrency{Currency: stored})
+	return currency, nil
+}
+
+// UpdateCurrency изменяет заданные поля валюты, nil поля остаются прежними.
+// Нулевой MaxAge сбрасывает допустимый возраст курса к значению по умолчанию.
+func (m *MemoryRepository) UpdateCurrency(ctx context.Context, id int, update models.CurrencyUpdate) (models.Currency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currency := m.currency(id)
+	if currency == nil || currency.deleted {
+		return models.Currency{}, ErrNotFound
+	}
+	if update.NameCurrency != nil && m.nameTaken(*update.NameCurrency, id) {
+		return models.Currency{}, ErrConflict
+	}
+
+	if update.NameCurrency != nil {
+		currency.NameCurrency = *update.NameCurrency
+	}
+	if update.DisplayName != nil {
+		currency.DisplayName = *update.DisplayName
+	}
+	if update.Symbol != nil {
+		currency.Symbol = *update.Symbol
+	}
+	if update.IsTracked != nil {
+		currency.IsTracked = *update.IsTracked
+	}
+	if update.MaxAge != nil {
+		currency.MaxAge = update.MaxAge.Truncate(time.Second)
+	}
+	return currency.Currency, nil
+}
+
+// nameTaken занято ли имя другой неудалённой валютой, кроме exceptID
+func (m *MemoryRepository) nameTaken(name string, exceptID int) bool {
+	for _, currency := range m.currencies {
+		if currency.ID != exceptID && !currency.deleted && currency.NameCurrency == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteCurrency помечает валюту удалённой и прекращает загрузку её курсов.
+// История курсов сохраняется.
+func (m *MemoryRepository) DeleteCurrency(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currency := m.currency(id)
+	if currency == nil || currency.deleted {
+		return ErrNotFound
+	}
+	currency.deleted = true
+	currency.IsTracked = false
+	return nil
+}
+
+// user возвращает пользователя, создавая его при первом обращении
+func (m *MemoryRepository) user(userID int64) *memoryUser {
+	user, ok := m.users[userID]
+	if !ok {
+		user = &memoryUser{active: make(map[int]bool)}
+		m.users[userID] = user
+	}
+	return user
+}
+
+// EnsureUser создает пользователя или обновляет имя если оно не пустое
+func (m *MemoryRepository) EnsureUser(ctx context.Context, userID int64, userName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.user(userID)
+	if userName != "" {
+		user.name = userName
+	}
+	return nil
+}
+
+// SetUserInterval устанавливает интервал автоотправки и активирует все валюты
+func (m *MemoryRepository) SetUserInterval(ctx context.Context, userID int64, interval int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.user(userID)
+	user.hasSettings = true
+	user.interval = interval
+	user.lastSent = memoryNow()
+	for _, currency := range m.currencies {
+		if !currency.deleted {
+			user.active[currency.ID] = true
+		}
+	}
+	return nil
+}
+
+// StopAuto отключает автоотправку
+func (m *MemoryRepository) StopAuto(ctx context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[userID]; ok {
+		user.interval = 0
+	}
+	return nil
+}
+
+// GetSubscribedUsers возвращает пользователей с активными подписками
+func (m *MemoryRepository) GetSubscribedUsers(ctx context.Context) ([]models.UserSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userIDs := make([]int64, 0, len(m.users))
+	for userID := range m.users {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	var users []models.UserSettings
+	for _, userID := range userIDs {
+		user := m.users[userID]
+		if !user.hasSettings || user.interval <= 0 {
+			continue
+		}
+
+		settings := models.UserSettings{
+			UserID:   userID,
+			Interval: user.interval,
+			LastSent: user.lastSent,
+			Timezone: user.timezone,
+		}
+		for _, currency := range m.currencies {
+			if user.active[currency.ID] && !currency.deleted {
+				settings.Currencies = append(settings.Currencies, models.Currency{
+					ID:           currency.ID,
+					NameCurrency: currency.NameCurrency,
+					DisplayName:  currency.DisplayName,
+					Symbol:       currency.Symbol,
+				})
+			}
+		}
+		if len(settings.Currencies) > 0 {
+			users = append(users, settings)
+		}
+	}
+	return users, nil
+}
+
+// SetUserTimezone сохраняет часовой пояс IANA пользователя, пустая строка сбрасывает его на UTC
+func (m *MemoryRepository) SetUserTimezone(ctx context.Context, userID int64, timezone string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.user(userID).timezone = timezone
+	return nil
+}
+
+// GetUserTimezone возвращает часовой пояс IANA пользователя, пустая строка - UTC
+func (m *MemoryRepository) GetUserTimezone(ctx context.Context, userID int64) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if user, ok := m.users[userID]; ok {
+		return user.timezone, nil
+	}
+	return "", nil
+}
+
+// UpdateLastSent обновляет время последней отправки
+func (m *MemoryRepository) UpdateLastSent(ctx context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[userID]; ok && user.hasSettings {
+		user.lastSent = memoryNow()
+	}
+	return nil
+}
+
+// GetLatestRates возвращает последний курс каждой валюты в котировке quote
+func (m *MemoryRepository) GetLatestRates(ctx context.Context, quote string) ([]models.CurrencyRateView, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	quote = quoteOrDefault(quote)
+	var rates []models.CurrencyRateView
+	for _, currency := range m.currenciesByName() {
+		series := m.series(currency.ID, quote)
+		if len(series) == 0 {
+			continue
+		}
+		latest := series[len(series)-1]
+		rates = append(rates, models.CurrencyRateView{
+			NameCurrency: currency.NameCurrency,
+			Price:        latest.Price,
+			Quote:        quote,
+			RecordedAt:   latest.RecordedAt,
+			CurrencyID:   currency.ID,
+			Market:       copyMarket(latest.Market),
+			MaxAge:       currency.MaxAge,
+		})
+	}
+	return rates, nil
+}
+
+// currenciesByName возвращает неудалённые валюты по возрастанию имени
+func (m *MemoryRepository) currenciesByName() []memoryCurrency {
+	var currencies []memoryCurrency
+	for _, currency := range m.currencies {
+		if !currency.deleted {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.SliceStable(currencies, func(i, j int) bool {
+		return currencies[i].NameCurrency < currencies[j].NameCurrency
+	})
+	return currencies
+}
+
+// GetCurrencyRate возвращает последний курс для валюты в указанной валюте котировки
+func (m *MemoryRepository) GetCurrencyRate(ctx context.Context, currencyID int, quote string) (models.ExchangeRate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	series := m.series(currencyID, quote)
+	if len(series) == 0 {
+		return models.ExchangeRate{}, sql.ErrNoRows
+	}
+	latest := series[len(series)-1]
+	return models.ExchangeRate{
+		ID:         latest.ID,
+		CurrencyID: latest.CurrencyID,
+		Price:      latest.Price,
+		Quote:      latest.Quote,
+		RecordedAt: latest.RecordedAt,
+		Market:     copyMarket(latest.Market),
+		MaxAge:     m.currency(currencyID).MaxAge,
+	}, nil
+}
+
+// GetRateSummaries возвращает последний курс, описание валюты, дневные
+// минимум и максимум и изменения за час, день и 24 часа.
+// Без currencyIDs возвращаются все валюты, у которых есть курс в котировке quote.
+// День - текущий календарный день в часовом поясе loc.
+func (m *MemoryRepository) GetRateSummaries(ctx context.Context, quote string, loc *time.Location, currencyIDs ...int) ([]models.RateSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	selected := make(map[int]bool, len(currencyIDs))
+	for _, id := range currencyIDs {
+		selected[id] = true
+	}
+
+	quote = quoteOrDefault(quote)
+	now := time.Now()
+	dayStart, dayEnd := models.CalendarDay(now, loc)
+
+	var summaries []models.RateSummary
+	for _, currency := range m.currenciesByName() {
+		if len(selected) > 0 && !selected[currency.ID] {
+			continue
+		}
+		series := m.series(currency.ID, quote)
+		if len(series) == 0 {
+			continue
+		}
+
+		latest := series[len(series)-1]
+		summary := models.RateSummary{
+			CurrencyID:   currency.ID,
+			NameCurrency: currency.NameCurrency,
+			Symbol:       currency.Symbol,
+			DisplayName:  currency.DisplayName,
+			Quote:        quote,
+			Price:        latest.Price,
+			RecordedAt:   latest.RecordedAt,
+			Market:       copyMarket(latest.Market),
+			MaxAge:       currency.MaxAge,
+		}
+		if today := between(series, dayStart, dayEnd); len(today) > 0 {
+			summary.DailyMin, summary.DailyMax = priceRange(today)
+			summary.DayChange = percentChange(models.NullDecimal{Decimal: today[0].Price, Valid: true}, summary.Price)
+		}
+		summary.HourlyChange = percentChange(priceAt(series, now.Add(-time.Hour)), summary.Price)
+		summary.Change24h = percentChange(priceAt(series, now.Add(-24*time.Hour)), summary.Price)
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// priceRange возвращает минимальную и максимальную цену непустого ряда
+func priceRange(rates []models.ExchangeRate) (min, max models.Decimal) {
+	min, max = rates[0].Price, rates[0].Price
+	for _, rate := range rates[1:] {
+		if rate.Price.Cmp(min) < 0 {
+			min = rate.Price
+		}
+		if rate.Price.Cmp(max) > 0 {
+			max = rate.Price
+		}
+	}
+	return min, max
+}
+
+// GetRateHistory возвращает курсы валюты за период по возрастанию времени,
+// не больше query.Limit записей
+func (m *MemoryRepository) GetRateHistory(ctx context.Context, query models.HistoryQuery) ([]models.HistoryPoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var points []models.HistoryPoint
+	for _, rate := range between(m.series(query.CurrencyID, query.Quote), query.From, query.To) {
+		if len(points) >= query.Limit {
+			break
+		}
+		if query.After != nil && !afterCursor(rate, *query.After) {
+			continue
+		}
+		points = append(points, models.HistoryPoint{ID: rate.ID, Time: rate.RecordedAt, Price: rate.Price})
+	}
+	return points, nil
+}
+
+// afterCursor идёт ли курс после курсора в порядке (recorded_at, id)
+func afterCursor(rate models.ExchangeRate, cursor models.HistoryCursor) bool {
+	if !rate.RecordedAt.Equal(cursor.Time) {
+		return rate.RecordedAt.After(cursor.Time)
+	}
+	return rate.ID > cursor.ID
+}
+
+// GetCandles строит свечи OHLC за период. Интервалы отсчитываются
+// от начала эпохи Unix, интервалы без курсов пропускаются.
+func (m *MemoryRepository) GetCandles(ctx context.Context, query models.CandleQuery) ([]models.Candle, error) {
+	step := int64(query.Interval.Seconds())
+	if step <= 0 {
+		return nil, fmt.Errorf("invalid candle interval: %s", query.Interval)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var candles []models.Candle
+	for _, rate := range between(m.series(query.CurrencyID, query.Quote), query.From, query.To) {
+		bucket := time.Unix(rate.RecordedAt.Unix()/step*step, 0).UTC()
+		if len(candles) == 0 || !candles[len(candles)-1].Time.Equal(bucket) {
+			candles = append(candles, models.Candle{
+				Time: bucket, Open: rate.Price, High: rate.Price, Low: rate.Price,
+			})
+		}
+
+		candle := &candles[len(candles)-1]
+		if rate.Price.Cmp(candle.High) > 0 {
+			candle.High = rate.Price
+		}
+		if rate.Price.Cmp(candle.Low) < 0 {
+			candle.Low = rate.Price
+		}
+		candle.Close = rate.Price
+		candle.Samples++
+	}
+	return candles, nil
+}
+
+// GetDailyMinMax возвращает минимальную и максимальную цену за сегодня.
+// Сегодня - текущий календарный день в часовом поясе loc.
+func (m *MemoryRepository) GetDailyMinMax(ctx context.Context, currencyID int, quote string, loc *time.Location) (min, max models.Decimal, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dayStart, dayEnd := models.CalendarDay(time.Now(), loc)
+	if today := between(m.series(currencyID, quote), dayStart, dayEnd); len(today) > 0 {
+		min, max = priceRange(today)
+	}
+	return min, max, nil
+}
+
+// GetDayChange возвращает изменение цены с начала сегодняшнего дня в процентах:
+// от первого курса дня до последнего. Сегодня - календарный день в часовом поясе loc.
+func (m *MemoryRepository) GetDayChange(ctx context.Context, currencyID int, quote string, loc *time.Location) (float64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dayStart, dayEnd := models.CalendarDay(time.Now(), loc)
+	today := between(m.series(currencyID, quote), dayStart, dayEnd)
+	if len(today) == 0 {
+		return 0, nil
+	}
+	open := models.NullDecimal{Decimal: today[0].Price, Valid: true}
+	return percentChange(open, today[len(today)-1].Price), nil
+}
+
+// GetHourlyChange возвращает изменение цены за последний час в процентах
+func (m *MemoryRepository) GetHourlyChange(ctx context.Context, currencyID int, quote string) (float64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	series := m.series(currencyID, quote)
+	if len(series) == 0 {
+		return 0, sql.ErrNoRows
+	}
+	hourAgo := priceAt(series, time.Now().Add(-time.Hour))
+	return percentChange(hourAgo, series[len(series)-1].Price), nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// EnsurePartitions создаёт месячные секции Exchange_rate для всех месяцев
+// с from по to включительно. Существующие секции не меняются.
+// Возвращает число созданных секций.
+//
+// Ограничение queryTimeout действует на каждый месяц отдельно,
+// чтобы бэкфилл за несколько лет не упирался в общий срок.
+func (r *Repository) EnsurePartitions(ctx context.Context, from, to time.Time) (int, error) {
+	created := 0
+	for month := monthStart(from); !month.After(to.UTC()); month = month.AddDate(0, 1, 0) {
+		ok, err := r.ensurePartition(ctx, month)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// ensurePartition создаёт секцию месяца month, если её ещё нет
+func (r *Repository) ensurePartition(ctx context.Context, month time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var ok bool
+	err := r.db.QueryRowContext(ctx, "SELECT ensure_exchange_rate_partition($1)", month).Scan(&ok)
+	return ok, err
+}
+
+// monthStart возвращает полночь UTC первого числа месяца t
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
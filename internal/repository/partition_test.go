@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepository_EnsurePartitions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Период внутри месяцев: секции создаются с первого числа каждого
+	months := []time.Time{
+		time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for i, month := range months {
+		mock.ExpectQuery(`SELECT ensure_exchange_rate_partition\(\$1\)`).
+			WithArgs(month).
+			WillReturnRows(sqlmock.NewRows([]string{"ensure_exchange_rate_partition"}).AddRow(i > 0))
+	}
+
+	from := time.Date(2024, 11, 17, 15, 30, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	created, err := repo.EnsurePartitions(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("EnsurePartitions failed: %v", err)
+	}
+	if created != 2 {
+		t.Errorf("Expected 2 partitions created, got %d", created)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRepository_EnsurePartitions_TimeoutPerMonth(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// Каждый месяц укладывается в ограничение, все вместе - нет
+	repo := NewRepository(db, WithQueryTimeout(100*time.Millisecond))
+	for month := 1; month <= 4; month++ {
+		mock.ExpectQuery(`SELECT ensure_exchange_rate_partition\(\$1\)`).
+			WithArgs(time.Date(2024, time.Month(month), 1, 0, 0, 0, 0, time.UTC)).
+			WillDelayFor(40 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"ensure_exchange_rate_partition"}).AddRow(true))
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	created, err := repo.EnsurePartitions(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("EnsurePartitions failed: %v", err)
+	}
+	if created != 4 {
+		t.Errorf("Expected 4 partitions created, got %d", created)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRepository_EnsurePartitions_MovesDefaultRows(t *testing.T) {
+	db := openTestDB(t)
+	truncateTestDB(t, db)
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	// Месяц в прошлом без секции: курсы попадают в Exchange_rate_default
+	month := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	dropPartition := func() {
+		if _, err := db.Exec("DROP TABLE IF EXISTS exchange_rate_2019_03"); err != nil {
+			t.Fatalf("Failed to drop partition: %v", err)
+		}
+	}
+	dropPartition()
+	t.Cleanup(dropPartition)
+
+	currency := mustCreateCurrency(t, repo, "bitcoin", "BTC")
+	points := []models.PricePoint{
+		{Time: month, Price: models.DecimalFromInt(100)},
+		{Time: month.Add(15 * 24 * time.Hour), Price: models.DecimalFromInt(110)},
+		{Time: month.AddDate(0, 1, 0).Add(-time.Second), Price: models.DecimalFromInt(120)},
+		{Time: month.AddDate(0, 1, 0), Price: models.DecimalFromInt(130)},
+	}
+	if _, err := repo.SaveHistoricalRates(ctx, currency.ID, "usd", "test", points); err != nil {
+		t.Fatalf("SaveHistoricalRates failed: %v", err)
+	}
+
+	created, err := repo.EnsurePartitions(ctx, month, month)
+	if err != nil {
+		t.Fatalf("EnsurePartitions failed: %v", err)
+	}
+	if created != 1 {
+		t.Errorf("Expected 1 partition created, got %d", created)
+	}
+
+	// Курсы месяца перенесены в секцию, следующий месяц остался в Exchange_rate_default
+	var inPartition, inDefault, total int
+	err = db.QueryRow(`
+        SELECT (SELECT COUNT(*) FROM exchange_rate_2019_03),
+            (SELECT COUNT(*) FROM Exchange_rate_default WHERE currency_id = $1),
+            (SELECT COUNT(*) FROM Exchange_rate WHERE currency_id = $1)`, currency.ID).
+		Scan(&inPartition, &inDefault, &total)
+	if err != nil {
+		t.Fatalf("Failed to count rates: %v", err)
+	}
+	if inPartition != 3 || inDefault != 1 || total != 4 {
+		t.Errorf("Expected 3 rates in partition, 1 in default, 4 total; got %d, %d, %d", inPartition, inDefault, total)
+	}
+}
@@ -1,54 +1,425 @@
 package repository
 
 import (
+	"context"
 	"cryptorate-service/internal/models"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound запись не найдена
+	ErrNotFound = errors.New("not found")
+	// ErrConflict запись с таким уникальным значением уже существует
+	ErrConflict = errors.New("already exists")
 )
 
+// uniqueViolation код ошибки PostgreSQL при нарушении уникальности
+const uniqueViolation = "23505"
+
+// mapError приводит ошибки драйвера к ошибкам репозитория
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrConflict
+	}
+	return err
+}
+
+// DefaultQueryTimeout ограничение времени одного вызова репозитория по умолчанию
+const DefaultQueryTimeout = 5 * time.Second
+
 type Repository struct {
 	db *sql.DB
+	// queryTimeout ограничение времени одного вызова, ноль - без ограничения
+	queryTimeout time.Duration
 }
 
-func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+// Option настройка Repository при создании
+type Option func(*Repository)
+
+// WithQueryTimeout задаёт ограничение времени одного вызова репозитория.
+// Ноль отключает ограничение, остаётся только отмена переданного контекста.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(r *Repository) {
+		r.queryTimeout = timeout
+	}
 }
 
-func (r *Repository) Ping() error {
-    return r.db.Ping()
+// ParseQueryTimeout разбирает ограничение времени вызова в формате
+// time.ParseDuration (например 10s или 500ms). Пустая строка даёт
+// DefaultQueryTimeout, ноль отключает ограничение.
+func ParseQueryTimeout(spec string) (time.Duration, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return DefaultQueryTimeout, nil
+	}
+	timeout, err := time.ParseDuration(spec)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid query timeout %q: expected a non-negative duration like 10s", spec)
+	}
+	return timeout, nil
+}
+
+func NewRepository(db *sql.DB, opts ...Option) *Repository {
+	r := &Repository{db: db, queryTimeout: DefaultQueryTimeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// withTimeout ограничивает контекст вызова временем queryTimeout
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.db.PingContext(ctx)
 }
 
 // SaveRate saves the currency exchange rate in the database
-func (r *Repository) SaveRate(rate models.ExchangeRate) error {
-	_, err := r.db.Exec(`
-        INSERT INTO exchange_rate (currency_id, price)
-        VALUES ($1, $2)`,
-		rate.CurrencyID, rate.Price)
+// together with the sources that contributed to it and market data if present
+func (r *Repository) SaveRate(ctx context.Context, rate models.ExchangeRate) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	sources := strings.Join(rate.Sources, ",")
+	marketCap, volume, change := marketToNull(rate.Market)
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO exchange_rate (currency_id, price, quote_currency, sources, rejected_count,
+            market_cap, volume_24h, change_24h)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		rate.CurrencyID, rate.Price, quoteOrDefault(rate.Quote),
+		sql.NullString{String: sources, Valid: sources != ""}, rate.Rejected,
+		marketCap, volume, change)
 	return err
 }
 
-// GetCurrencyID возвращает ID валюты по её имени
-func (r *Repository) GetCurrencyID(name string) (int, error) {
+// SaveRates сохраняет курсы одного цикла загрузки в одной транзакции:
+// либо сохраняются все курсы, либо ни одного. Все курсы получают общее
+// время recorded_at, чтобы снимок можно было сравнивать между валютами.
+// Возвращает время, с которым сохранены курсы.
+func (r *Repository) SaveRates(ctx context.Context, rates []models.ExchangeRate) (time.Time, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	recordedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if len(rates) == 0 {
+		return recordedAt, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(rates); start += historyBatchSize {
+		end := start + historyBatchSize
+		if end > len(rates) {
+			end = len(rates)
+		}
+
+		values := make([]string, 0, end-start)
+		args := []interface{}{recordedAt}
+		for _, rate := range rates[start:end] {
+			sources := strings.Join(rate.Sources, ",")
+			marketCap, volume, change := marketToNull(rate.Market)
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $1)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+			args = append(args, rate.CurrencyID, rate.Price, quoteOrDefault(rate.Quote),
+				sql.NullString{String: sources, Valid: sources != ""}, rate.Rejected,
+				marketCap, volume, change)
+		}
+
+		_, err := tx.ExecContext(ctx, `
+        INSERT INTO exchange_rate (currency_id, price, quote_currency, sources, rejected_count,
+            market_cap, volume_24h, change_24h, recorded_at)
+        VALUES `+strings.Join(values, ", "), args...)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, err
+	}
+	return recordedAt, nil
+}
+
+// marketToNull раскладывает рыночные показатели по nullable колонкам
+func marketToNull(market *models.MarketData) (marketCap, volume, change sql.NullFloat64) {
+	if market == nil {
+		return
+	}
+	return sql.NullFloat64{Float64: market.MarketCap, Valid: true},
+		sql.NullFloat64{Float64: market.Volume24h, Valid: true},
+		sql.NullFloat64{Float64: market.Change24h, Valid: true}
+}
+
+// marketFromNull собирает рыночные показатели из колонок, nil если их нет
+func marketFromNull(marketCap, volume, change sql.NullFloat64) *models.MarketData {
+	if !marketCap.Valid && !volume.Valid && !change.Valid {
+		return nil
+	}
+	return &models.MarketData{
+		MarketCap: marketCap.Float64,
+		Volume24h: volume.Float64,
+		Change24h: change.Float64,
+	}
+}
+
+// historyBatchSize число строк в одном INSERT при загрузке истории
+// и сохранении цикла (ограничение PostgreSQL - 65535 параметров на запрос)
+const historyBatchSize = 1000
+
+// historyDedupWindow исторический курс не сохраняется, если в ряду уже есть
+// курс ближе этого интервала: повторная загрузка со сдвинутым периодом
+// и загрузка поверх курсов воркера не добавляют почти совпадающих точек
+const historyDedupWindow = 30 * time.Minute
+
+// SaveHistoricalRates сохраняет исторические курсы с исходным временем.
+// Курсы, рядом с которыми (ближе historyDedupWindow) уже есть запись,
+// пропускаются, поэтому загрузку истории можно безопасно повторять.
+// Возвращает число добавленных записей.
+func (r *Repository) SaveHistoricalRates(ctx context.Context, currencyID int, quote, source string, points []models.PricePoint) (int64, error) {
+	quote = quoteOrDefault(quote)
+	sources := sql.NullString{String: source, Valid: source != ""}
+
+	var inserted int64
+	for start := 0; start < len(points); start += historyBatchSize {
+		end := start + historyBatchSize
+		if end > len(points) {
+			end = len(points)
+		}
+		batch := points[start:end]
+
+		values := make([]string, len(batch))
+		args := []interface{}{currencyID, quote, sources, historyDedupWindow.Seconds()}
+		for i, point := range batch {
+			values[i] = fmt.Sprintf("($%d::timestamptz, $%d::numeric)", len(args)+1, len(args)+2)
+			args = append(args, point.Time.UTC(), point.Price)
+		}
+
+		// Ограничение времени действует на каждую пачку, а не на всю загрузку
+		batchCtx, cancel := r.withTimeout(ctx)
+		result, err := r.db.ExecContext(batchCtx, `
+        INSERT INTO exchange_rate (currency_id, price, quote_currency, sources, recorded_at)
+        SELECT DISTINCT ON (v.recorded_at) $1::int, v.price, $2::varchar, $3::varchar, v.recorded_at
+        FROM (VALUES `+strings.Join(values, ", ")+`) AS v(recorded_at, price)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM exchange_rate e
+            WHERE e.currency_id = $1 AND e.quote_currency = $2
+            AND e.recorded_at > v.recorded_at - $4 * INTERVAL '1 second'
+            AND e.recorded_at < v.recorded_at + $4 * INTERVAL '1 second'
+        )`, args...)
+		cancel()
+		if err != nil {
+			return inserted, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return inserted, err
+		}
+		inserted += affected
+	}
+
+	return inserted, nil
+}
+
+// ConsumeRequestBudget учитывает запрос к внешнему API в общем для всех процессов
+// минутном окне. Возвращает false, если лимит окна уже исчерпан.
+// Реализует api.RequestBudget.
+func (r *Repository) ConsumeRequestBudget(ctx context.Context, provider string, windowStart time.Time, limit int) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var requests int
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO api_request_budget (provider, window_start, requests)
+        VALUES ($1, $2, 1)
+        ON CONFLICT (provider, window_start) DO UPDATE
+        SET requests = api_request_budget.requests + 1
+        WHERE api_request_budget.requests < $3
+        RETURNING requests`, provider, windowStart.UTC(), limit).Scan(&requests)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Первый запрос в новом окне удаляет устаревшие окна этого источника
+	if requests == 1 {
+		_, err = r.db.ExecContext(ctx, `
+        DELETE FROM api_request_budget
+        WHERE provider = $1 AND window_start < $2`, provider, windowStart.UTC().Add(-time.Hour))
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// StartFetchRun записывает начало цикла загрузки курсов и возвращает его ID
+func (r *Repository) StartFetchRun(ctx context.Context, provider string, startedAt time.Time) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var id int
-	err := r.db.QueryRow("SELECT id FROM currency WHERE LOWER(name_currency) = LOWER($1)", name).Scan(&id)
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO fetch_runs (started_at, provider, status)
+        VALUES ($1, $2, $3)
+        RETURNING id`, startedAt.UTC(), provider, models.FetchRunRunning).Scan(&id)
 	return id, err
 }
 
-// GetLatestRates вовращает послдний курс каждой вылюты из БД
-func (r *Repository) GetLatestRates() ([]models.CurrencyRateView, error) {
-	// SQL запрос: для каждой валюты берём самую свежую запись
+// FinishFetchRun сохраняет итоги цикла загрузки курсов
+func (r *Repository) FinishFetchRun(ctx context.Context, run models.FetchRun) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	finishedAt := time.Now().UTC()
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.UTC()
+	}
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE fetch_runs
+        SET finished_at = $2, coins_requested = $3, rates_saved = $4,
+            coins_skipped = $5, status = $6, error = $7
+        WHERE id = $1`,
+		run.ID, finishedAt, run.CoinsRequested, run.RatesSaved, run.CoinsSkipped, run.Status,
+		sql.NullString{String: run.Error, Valid: run.Error != ""})
+	return err
+}
+
+// GetFetchRuns возвращает последние циклы загрузки курсов, новые первыми
+func (r *Repository) GetFetchRuns(ctx context.Context, limit int) ([]models.FetchRun, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, started_at, finished_at, provider, coins_requested, rates_saved,
+            coins_skipped, status, error
+        FROM fetch_runs
+        ORDER BY started_at DESC
+        LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.FetchRun
+	for rows.Next() {
+		var run models.FetchRun
+		var finishedAt sql.NullTime
+		var errText sql.NullString
+		err := rows.Scan(&run.ID, &run.StartedAt, &finishedAt, &run.Provider, &run.CoinsRequested,
+			&run.RatesSaved, &run.CoinsSkipped, &run.Status, &errText)
+		if err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		run.Error = errText.String
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetLastSuccessfulFetch возвращает время завершения последнего цикла,
+// в котором был сохранён хотя бы один курс. ErrNotFound, если таких циклов нет.
+func (r *Repository) GetLastSuccessfulFetch(ctx context.Context) (time.Time, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var finishedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+        SELECT MAX(finished_at)
+        FROM fetch_runs
+        WHERE rates_saved > 0`).Scan(&finishedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !finishedAt.Valid {
+		return time.Time{}, ErrNotFound
+	}
+	return finishedAt.Time, nil
+}
+
+// quoteOrDefault приводит валюту котировки к виду, в котором она хранится в БД
+func quoteOrDefault(quote string) string {
+	quote = strings.ToLower(strings.TrimSpace(quote))
+	if quote == "" {
+		return models.DefaultQuote
+	}
+	return quote
+}
+
+// GetCurrencyID возвращает ID валюты по её имени. Если валюту удаляли
+// и добавляли заново, возвращается действующая, иначе последняя удалённая.
+func (r *Repository) GetCurrencyID(ctx context.Context, name string) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id FROM currency WHERE LOWER(name_currency) = LOWER($1) ORDER BY deleted_at IS NOT NULL, id DESC LIMIT 1",
+		name).Scan(&id)
+	return id, err
+}
+
+// GetLatestRates вовращает послдний курс каждой вылюты из БД в указанной валюте котировки
+func (r *Repository) GetLatestRates(ctx context.Context, quote string) ([]models.CurrencyRateView, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	// SQL запрос: для каждой валюты берём самую свежую запись.
+	// LATERAL читает одну строку обратным проходом по индексу
+	// idx_exchange_rate_currency_quote_time вместо сортировки всех курсов.
 	query := `
-        SELECT DISTINCT ON (c.name_currency)
+        SELECT
             c.name_currency,
             e.price,
             e.recorded_at,
-            c.id as currency_id
+            c.id as currency_id,
+            e.market_cap,
+            e.volume_24h,
+            e.change_24h,
+            c.max_age_seconds
         FROM currency c
-        JOIN exchange_rate e ON c.id = e.currency_id
-        ORDER BY c.name_currency, e.recorded_at DESC`
+        CROSS JOIN LATERAL (
+            SELECT price, recorded_at, market_cap, volume_24h, change_24h
+            FROM exchange_rate
+            WHERE currency_id = c.id AND quote_currency = $1
+            ORDER BY recorded_at DESC, id DESC
+            LIMIT 1
+        ) e
+        WHERE c.deleted_at IS NULL
+        ORDER BY c.name_currency`
 
-	rows, err := r.db.Query(query)
+	quote = quoteOrDefault(quote)
+	rows, err := r.db.QueryContext(ctx, query, quote)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -56,12 +427,16 @@ func (r *Repository) GetLatestRates() ([]models.CurrencyRateView, error) {
 
 	var rates []models.CurrencyRateView
 	for rows.Next() {
-		var rate models.CurrencyRateView
-		// ДОБАВЬТЕ CurrencyID
-		err := rows.Scan(&rate.NameCurrency, &rate.Price, &rate.RecordedAt, &rate.CurrencyID)
+		rate := models.CurrencyRateView{Quote: quote}
+		var marketCap, volume, change sql.NullFloat64
+		var maxAge sql.NullInt64
+		err := rows.Scan(&rate.NameCurrency, &rate.Price, &rate.RecordedAt, &rate.CurrencyID,
+			&marketCap, &volume, &change, &maxAge)
 		if err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
+		rate.Market = marketFromNull(marketCap, volume, change)
+		rate.MaxAge = maxAgeFromNull(maxAge)
 		rates = append(rates, rate)
 	}
 
@@ -69,24 +444,33 @@ func (r *Repository) GetLatestRates() ([]models.CurrencyRateView, error) {
 }
 
 // GetCurrencySymbol возвращает символ валюты по её ID
-func (r *Repository) GetCurrencySymbol(currencyID int) (string, error) {
+func (r *Repository) GetCurrencySymbol(ctx context.Context, currencyID int) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var symbol string
-	err := r.db.QueryRow("SELECT symbol FROM currency WHERE id = $1", currencyID).Scan(&symbol)
+	err := r.db.QueryRowContext(ctx, "SELECT symbol FROM currency WHERE id = $1", currencyID).Scan(&symbol)
 	return symbol, err
 }
 
 // GetCurrencyDisplayName возвращает отображаемое имя валюты
-func (r *Repository) GetCurrencyDisplayName(currencyID int) (string, error) {
+func (r *Repository) GetCurrencyDisplayName(ctx context.Context, currencyID int) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var displayName string
-	err := r.db.QueryRow("SELECT display_name FROM currency WHERE id = $1", currencyID).Scan(&displayName)
+	err := r.db.QueryRowContext(ctx, "SELECT display_name FROM currency WHERE id = $1", currencyID).Scan(&displayName)
 	return displayName, err
 }
 
-// GetAllCurrencies возвращает все доступные валюты
-func (r *Repository) GetAllCurrencies() ([]models.Currency, error) {
-	query := "SELECT id, name_currency, display_name, symbol FROM currency ORDER BY id"
+// GetAllCurrencies возвращает все доступные валюты, кроме удалённых
+func (r *Repository) GetAllCurrencies(ctx context.Context) ([]models.Currency, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT id, name_currency, display_name, symbol, is_tracked, max_age_seconds FROM currency WHERE deleted_at IS NULL ORDER BY id"
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -95,31 +479,206 @@ func (r *Repository) GetAllCurrencies() ([]models.Currency, error) {
 	var currencies []models.Currency
 	for rows.Next() {
 		var currency models.Currency
+		var maxAge sql.NullInt64
 		err := rows.Scan(&currency.ID, &currency.NameCurrency,
-			&currency.DisplayName, &currency.Symbol)
+			&currency.DisplayName, &currency.Symbol, &currency.IsTracked, &maxAge)
 		if err != nil {
 			return nil, err
 		}
+		currency.MaxAge = maxAgeFromNull(maxAge)
 		currencies = append(currencies, currency)
 	}
 
 	return currencies, nil
 }
 
+// GetCurrencyByID возвращает валюту по ID, удалённые валюты не возвращаются
+func (r *Repository) GetCurrencyByID(ctx context.Context, id int) (models.Currency, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var currency models.Currency
+	var maxAge sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+        SELECT id, name_currency, display_name, symbol, is_tracked, max_age_seconds
+        FROM currency
+        WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&currency.ID, &currency.NameCurrency,
+		&currency.DisplayName, &currency.Symbol, &currency.IsTracked, &maxAge)
+	currency.MaxAge = maxAgeFromNull(maxAge)
+	return currency, mapError(err)
+}
+
+// CreateCurrency добавляет новую валюту и возвращает её с присвоенным ID.
+// Имя уникально среди неудалённых валют, удалённую валюту можно добавить заново.
+func (r *Repository) CreateCurrency(ctx context.Context, currency models.Currency) (models.Currency, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO currency (name_currency, display_name, symbol, is_tracked, max_age_seconds)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id`,
+		currency.NameCurrency, currency.DisplayName, currency.Symbol, currency.IsTracked,
+		maxAgeToNull(currency.MaxAge)).Scan(&currency.ID)
+	if err != nil {
+		return models.Currency{}, mapError(err)
+	}
+	return currency, nil
+}
+
+// UpdateCurrency изменяет заданные поля валюты, nil поля остаются прежними.
+// Нулевой MaxAge сбрасывает допустимый возраст курса к значению по умолчанию.
+func (r *Repository) UpdateCurrency(ctx context.Context, id int, update models.CurrencyUpdate) (models.Currency, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var maxAgeUpdate sql.NullInt64
+	if update.MaxAge != nil {
+		maxAgeUpdate = sql.NullInt64{Int64: int64(update.MaxAge.Seconds()), Valid: true}
+	}
+
+	var currency models.Currency
+	var maxAge sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+        UPDATE currency SET
+            name_currency = COALESCE($2, name_currency),
+            display_name = COALESCE($3, display_name),
+            symbol = COALESCE($4, symbol),
+            is_tracked = COALESCE($5, is_tracked),
+            max_age_seconds = CASE WHEN $6::int IS NULL THEN max_age_seconds ELSE NULLIF($6::int, 0) END
+        WHERE id = $1 AND deleted_at IS NULL
+        RETURNING id, name_currency, display_name, symbol, is_tracked, max_age_seconds`,
+		id, update.NameCurrency, update.DisplayName, update.Symbol, update.IsTracked, maxAgeUpdate).Scan(
+		&currency.ID, &currency.NameCurrency, &currency.DisplayName, &currency.Symbol, &currency.IsTracked, &maxAge)
+	currency.MaxAge = maxAgeFromNull(maxAge)
+	return currency, mapError(err)
+}
+
+// maxAgeToNull сохраняет допустимый возраст курса в секундах, ноль - NULL
+func maxAgeToNull(maxAge time.Duration) sql.NullInt64 {
+	seconds := int64(maxAge.Seconds())
+	return sql.NullInt64{Int64: seconds, Valid: seconds > 0}
+}
+
+func maxAgeFromNull(seconds sql.NullInt64) time.Duration {
+	if !seconds.Valid {
+		return 0
+	}
+	return time.Duration(seconds.Int64) * time.Second
+}
+
+// GetTrackedFreshness возвращает время последнего курса в котировке quote
+// для каждой отслеживаемой валюты, включая валюты без курсов
+func (r *Repository) GetTrackedFreshness(ctx context.Context, quote string) ([]models.CurrencyFreshness, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT c.name_currency, c.max_age_seconds, e.recorded_at
+        FROM currency c
+        LEFT JOIN LATERAL (
+            SELECT recorded_at
+            FROM exchange_rate
+            WHERE currency_id = c.id AND quote_currency = $1
+            ORDER BY recorded_at DESC
+            LIMIT 1
+        ) e ON true
+        WHERE c.is_tracked AND c.deleted_at IS NULL
+        ORDER BY c.id`, quoteOrDefault(quote))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.CurrencyFreshness
+	for rows.Next() {
+		var item models.CurrencyFreshness
+		var maxAge sql.NullInt64
+		var recordedAt sql.NullTime
+		if err := rows.Scan(&item.NameCurrency, &maxAge, &recordedAt); err != nil {
+			return nil, err
+		}
+		item.MaxAge = maxAgeFromNull(maxAge)
+		if recordedAt.Valid {
+			item.LastRecordedAt = &recordedAt.Time
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteCurrency помечает валюту удалённой и прекращает загрузку её курсов.
+// История курсов в Exchange_rate сохраняется.
+func (r *Repository) DeleteCurrency(ctx context.Context, id int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE currency
+        SET deleted_at = NOW(), is_tracked = false
+        WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetTrackedCurrencies возвращает валюты, курсы которых должен загружать воркер
+func (r *Repository) GetTrackedCurrencies(ctx context.Context) ([]models.Currency, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT id, name_currency, display_name, symbol FROM currency WHERE is_tracked AND deleted_at IS NULL ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var currencies []models.Currency
+	for rows.Next() {
+		currency := models.Currency{IsTracked: true}
+		err := rows.Scan(&currency.ID, &currency.NameCurrency,
+			&currency.DisplayName, &currency.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, currency)
+	}
+
+	return currencies, rows.Err()
+}
+
 // GetCurrencyIDBySymbol возвращает ID валюты по символу (BTC, ETH)
-func (r *Repository) GetCurrencyIDBySymbol(symbol string) (int, error) {
+func (r *Repository) GetCurrencyIDBySymbol(ctx context.Context, symbol string) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var id int
-	err := r.db.QueryRow(
-		"SELECT id FROM currency WHERE LOWER(symbol) = LOWER($1)",
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id FROM currency WHERE LOWER(symbol) = LOWER($1) ORDER BY deleted_at IS NOT NULL, id DESC LIMIT 1",
 		symbol,
 	).Scan(&id)
 	return id, err
 }
 
 // GetCurrencySymbolByID возвращает символ валюты по ID
-func (r *Repository) GetCurrencySymbolByID(currencyID int) (string, error) {
+func (r *Repository) GetCurrencySymbolByID(ctx context.Context, currencyID int) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var symbol string
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(ctx,
 		"SELECT symbol FROM currency WHERE id = $1",
 		currencyID,
 	).Scan(&symbol)
@@ -127,8 +686,11 @@ func (r *Repository) GetCurrencySymbolByID(currencyID int) (string, error) {
 }
 
 // EnsureUser создает пользователя или обновляет имя если оно не пустое
-func (r *Repository) EnsureUser(userID int64, userName string) error {
-	_, err := r.db.Exec(`
+func (r *Repository) EnsureUser(ctx context.Context, userID int64, userName string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
         INSERT INTO Users (user_id, user_name)
         VALUES ($1, $2)
         ON CONFLICT (user_id) DO UPDATE SET
@@ -141,16 +703,19 @@ func (r *Repository) EnsureUser(userID int64, userName string) error {
 }
 
 // SetUserInterval устанавливает интервал автоотправки
-func (r *Repository) SetUserInterval(userID int64, interval int) error {
-	r.EnsureUser(userID, "")
+func (r *Repository) SetUserInterval(ctx context.Context, userID int64, interval int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	tx, err := r.db.Begin()
+	r.EnsureUser(ctx, userID, "")
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	// Обновляем или добавляем настройки
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
         INSERT INTO Settings (user_id, time_interval, last_sent)
         VALUES ($1, $2, NOW())
         ON CONFLICT (user_id)
@@ -162,10 +727,11 @@ func (r *Repository) SetUserInterval(userID int64, interval int) error {
 	}
 
 	// Активируем все валюты для пользователя
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
         INSERT INTO Currency_settings (user_id, currency_id, is_active)
         SELECT $1, id, true
         FROM Currency
+        WHERE deleted_at IS NULL
         ON CONFLICT (user_id, currency_id)
         DO UPDATE SET is_active = true
     `, userID)
@@ -178,8 +744,11 @@ func (r *Repository) SetUserInterval(userID int64, interval int) error {
 }
 
 // StopAuto отключает автоотправку
-func (r *Repository) StopAuto(userID int64) error {
-	_, err := r.db.Exec(`
+func (r *Repository) StopAuto(ctx context.Context, userID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
         UPDATE Settings
         SET time_interval = NULL
         WHERE user_id = $1
@@ -188,17 +757,21 @@ func (r *Repository) StopAuto(userID int64) error {
 }
 
 // GetSubscribedUsers возвращает пользователей с активными подписками
-func (r *Repository) GetSubscribedUsers() ([]models.UserSettings, error) {
+func (r *Repository) GetSubscribedUsers(ctx context.Context) ([]models.UserSettings, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-        SELECT s.user_id, s.time_interval, s.last_sent,
+        SELECT s.user_id, s.time_interval, s.last_sent, COALESCE(u.timezone, ''),
         c.id, c.name_currency, c.display_name, c.symbol
         FROM Settings s
+        JOIN Users u ON u.user_id = s.user_id
         JOIN Currency_settings cs ON s.user_id = cs.user_id AND cs.is_active = true
-        JOIN Currency c ON cs.currency_id = c.id
+        JOIN Currency c ON cs.currency_id = c.id AND c.deleted_at IS NULL
         WHERE s.time_interval > 0
         ORDER BY s.user_id`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -212,10 +785,11 @@ func (r *Repository) GetSubscribedUsers() ([]models.UserSettings, error) {
 		var userID int64
 		var interval int
 		var lastSent time.Time
+		var timezone string
 		var currencyID int
 		var nameCurrency, displayName, symbol string
 
-		err := rows.Scan(&userID, &interval, &lastSent,
+		err := rows.Scan(&userID, &interval, &lastSent, &timezone,
 			&currencyID, &nameCurrency, &displayName, &symbol)
 		if err != nil {
 			return nil, err
@@ -226,6 +800,7 @@ func (r *Repository) GetSubscribedUsers() ([]models.UserSettings, error) {
 				UserID:   userID,
 				Interval: interval,
 				LastSent: lastSent,
+				Timezone: timezone,
 				Currencies: []models.Currency{{ID: currencyID, NameCurrency: nameCurrency,
 					DisplayName: displayName, Symbol: symbol}},
 			})
@@ -241,9 +816,41 @@ func (r *Repository) GetSubscribedUsers() ([]models.UserSettings, error) {
 	return users, nil
 }
 
+// SetUserTimezone сохраняет часовой пояс IANA пользователя, пустая строка сбрасывает его на UTC
+func (r *Repository) SetUserTimezone(ctx context.Context, userID int64, timezone string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO Users (user_id, timezone)
+        VALUES ($1, NULLIF($2, ''))
+        ON CONFLICT (user_id) DO UPDATE SET timezone = NULLIF($2, '')
+    `, userID, timezone)
+	return err
+}
+
+// GetUserTimezone возвращает часовой пояс IANA пользователя, пустая строка - UTC
+func (r *Repository) GetUserTimezone(ctx context.Context, userID int64) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var timezone sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT timezone FROM Users WHERE user_id = $1",
+		userID,
+	).Scan(&timezone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return timezone.String, err
+}
+
 // UpdateLastSent обновляет время последней отправки
-func (r *Repository) UpdateLastSent(userID int64) error {
-	_, err := r.db.Exec(`
+func (r *Repository) UpdateLastSent(ctx context.Context, userID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
         UPDATE Settings
         SET last_sent = NOW()
         WHERE user_id = $1
@@ -251,63 +858,265 @@ func (r *Repository) UpdateLastSent(userID int64) error {
 	return err
 }
 
-// GetCurrencyRate возвращает последний курс для валюты
-func (r *Repository) GetCurrencyRate(currencyID int) (models.ExchangeRate, error) {
+// GetCurrencyRate возвращает последний курс для валюты в указанной валюте котировки
+func (r *Repository) GetCurrencyRate(ctx context.Context, currencyID int, quote string) (models.ExchangeRate, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var rate models.ExchangeRate
-	err := r.db.QueryRow(`
-        SELECT id, currency_id, price, recorded_at
-        FROM Exchange_rate
-        WHERE currency_id = $1
-        ORDER BY recorded_at DESC
-        LIMIT 1`, currencyID).Scan(&rate.ID, &rate.CurrencyID, &rate.Price, &rate.RecordedAt)
+	var marketCap, volume, change sql.NullFloat64
+	var maxAge sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+        SELECT e.id, e.currency_id, e.price, e.quote_currency, e.recorded_at, e.market_cap, e.volume_24h, e.change_24h,
+            c.max_age_seconds
+        FROM Exchange_rate e
+        JOIN currency c ON c.id = e.currency_id
+        WHERE e.currency_id = $1 AND e.quote_currency = $2
+        ORDER BY e.recorded_at DESC
+        LIMIT 1`, currencyID, quoteOrDefault(quote)).Scan(&rate.ID, &rate.CurrencyID, &rate.Price, &rate.Quote, &rate.RecordedAt,
+		&marketCap, &volume, &change, &maxAge)
+	rate.Market = marketFromNull(marketCap, volume, change)
+	rate.MaxAge = maxAgeFromNull(maxAge)
 	return rate, err
 }
 
-// GetDailyMinMax возвращает минимальную и максимальную цену за сегодня
-func (r *Repository) GetDailyMinMax(currencyID int) (min, max float64, err error) {
+// GetRateHistory возвращает курсы валюты за период по возрастанию времени,
+// не больше query.Limit записей. До границы удаления сырых курсов по сроку
+// хранения отдаётся цена открытия часового агрегата с ID 0, даже если
+// backfill добавил туда отдельные сырые курсы.
+func (r *Repository) GetRateHistory(ctx context.Context, query models.HistoryQuery) ([]models.HistoryPoint, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	sqlQuery := `
+        SELECT id, recorded_at, price FROM (
+            SELECT 0 AS id, bucket AS recorded_at, open AS price
+            FROM Exchange_rate_hourly
+            WHERE currency_id = $1 AND quote_currency = $2
+            AND bucket >= $3 AND bucket < $4
+            AND bucket < ` + prunedBeforeSQL + `
+            UNION ALL
+            SELECT id, recorded_at, price
+            FROM Exchange_rate
+            WHERE currency_id = $1 AND quote_currency = $2
+            AND recorded_at >= $3 AND recorded_at < $4
+            AND recorded_at >= ` + prunedBeforeSQL + `
+        ) history`
+	args := []interface{}{query.CurrencyID, quoteOrDefault(query.Quote), query.From.UTC(), query.To.UTC()}
+	if query.After != nil {
+		sqlQuery += `
+        WHERE (recorded_at, id) > ($5, $6)`
+		args = append(args, query.After.Time.UTC(), query.After.ID)
+	}
+	sqlQuery += fmt.Sprintf(`
+        ORDER BY recorded_at, id
+        LIMIT $%d`, len(args)+1)
+	args = append(args, query.Limit)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []models.HistoryPoint
+	for rows.Next() {
+		var point models.HistoryPoint
+		if err := rows.Scan(&point.ID, &point.Time, &point.Price); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// GetCandles строит свечи OHLC за период. Интервалы отсчитываются
+// от начала эпохи Unix, поэтому дневные свечи начинаются в полночь UTC.
+// Интервалы без курсов пропускаются.
+//
+// Свечи от часа собираются из агрегатов: целые часы (сутки), уже
+// агрегированные воркером, читаются из Exchange_rate_hourly (Exchange_rate_daily),
+// остальная часть периода - из сырых курсов. Сырые курсы до границы удаления
+// по сроку хранения не читаются: края периода дневных свечей до неё
+// собираются из часовых агрегатов.
+func (r *Repository) GetCandles(ctx context.Context, query models.CandleQuery) ([]models.Candle, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	from, to := query.From.UTC(), query.To.UTC()
+	args := []interface{}{query.CurrencyID, quoteOrDefault(query.Quote), from, to, int64(query.Interval.Seconds())}
+
+	sqlQuery := `
+        SELECT floor(extract(epoch FROM recorded_at) / $5)::bigint * $5 AS bucket,
+            (array_agg(price ORDER BY recorded_at, id))[1],
+            MAX(price),
+            MIN(price),
+            (array_agg(price ORDER BY recorded_at DESC, id DESC))[1],
+            COUNT(*)
+        FROM Exchange_rate
+        WHERE currency_id = $1 AND quote_currency = $2
+        AND recorded_at >= $3 AND recorded_at < $4
+        GROUP BY bucket
+        ORDER BY bucket`
+	if tier, ok := tierFor(query.Interval); ok {
+		// Агрегаты целиком внутри периода: [$6, $7)
+		rollupFrom := from.Truncate(tier.step)
+		if rollupFrom.Before(from) {
+			rollupFrom = rollupFrom.Add(tier.step)
+		}
+		args = append(args, rollupFrom, to.Truncate(tier.step))
+
+		// Целые часы на краях периода дневных свечей, сырые курсы которых
+		// уже удалены
+		hourlyEdges := ""
+		if tier == dailyTier {
+			hourlyEdges = `
+            UNION ALL
+            SELECT bucket, open, high, low, close, samples
+            FROM Exchange_rate_hourly
+            WHERE currency_id = $1 AND quote_currency = $2
+            AND bucket >= $3 AND bucket + INTERVAL '1 hour' <= $4
+            AND bucket < (SELECT before FROM pruned)
+            AND (bucket < $6 OR bucket >= LEAST($7, (SELECT bucket FROM watermark)))`
+		}
+
+		// Последний агрегат валюты может быть неполным, он и более поздние
+		// курсы читаются из Exchange_rate
+		sqlQuery = fmt.Sprintf(`
+        WITH watermark AS (
+            SELECT COALESCE(MAX(bucket), '-infinity') AS bucket FROM %[1]s
+            WHERE currency_id = $1 AND quote_currency = $2
+        ), pruned AS (
+            SELECT %[2]s AS before
+        ), source AS (
+            SELECT bucket AS recorded_at, open, high, low, close, samples
+            FROM %[1]s
+            WHERE currency_id = $1 AND quote_currency = $2
+            AND bucket >= $6 AND bucket < LEAST($7, (SELECT bucket FROM watermark))%[3]s
+            UNION ALL
+            SELECT recorded_at, price, price, price, price, 1
+            FROM Exchange_rate
+            WHERE currency_id = $1 AND quote_currency = $2
+            AND recorded_at >= $3 AND recorded_at < $4
+            AND recorded_at >= (SELECT before FROM pruned)
+            AND (recorded_at < $6 OR recorded_at >= LEAST($7, (SELECT bucket FROM watermark)))
+        )
+        SELECT floor(extract(epoch FROM recorded_at) / $5)::bigint * $5 AS bucket,
+            (array_agg(open ORDER BY recorded_at))[1],
+            MAX(high),
+            MIN(low),
+            (array_agg(close ORDER BY recorded_at DESC))[1],
+            SUM(samples)
+        FROM source
+        GROUP BY bucket
+        ORDER BY bucket`, tier.table, prunedBeforeSQL, hourlyEdges)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []models.Candle
+	for rows.Next() {
+		var candle models.Candle
+		var bucket int64
+		err := rows.Scan(&bucket, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Samples)
+		if err != nil {
+			return nil, err
+		}
+		candle.Time = time.Unix(bucket, 0).UTC()
+		candles = append(candles, candle)
+	}
+
+	return candles, rows.Err()
+}
+
+// GetDailyMinMax возвращает минимальную и максимальную цену за сегодня.
+// Сегодня - текущий календарный день в часовом поясе loc.
+func (r *Repository) GetDailyMinMax(ctx context.Context, currencyID int, quote string, loc *time.Location) (min, max models.Decimal, err error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	dayStart, dayEnd := models.CalendarDay(time.Now(), loc)
 	query := `
         SELECT MIN(price), MAX(price)
         FROM Exchange_rate
-        WHERE currency_id = $1
-        AND recorded_at >= CURRENT_DATE
-        AND recorded_at < CURRENT_DATE + INTERVAL '1 day'`
+        WHERE currency_id = $1 AND quote_currency = $2
+        AND recorded_at >= $3
+        AND recorded_at < $4`
 
-	err = r.db.QueryRow(query, currencyID).Scan(&min, &max)
+	err = r.db.QueryRowContext(ctx, query, currencyID, quoteOrDefault(quote), dayStart, dayEnd).Scan(&min, &max)
 	return
 }
 
+// GetDayChange возвращает изменение цены с начала сегодняшнего дня в процентах:
+// от первого курса дня до последнего. Сегодня - календарный день в часовом поясе loc.
+func (r *Repository) GetDayChange(ctx context.Context, currencyID int, quote string, loc *time.Location) (change float64, err error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	dayStart, dayEnd := models.CalendarDay(time.Now(), loc)
+	var open, last models.NullDecimal
+	err = r.db.QueryRowContext(ctx, `
+        SELECT
+            (SELECT price FROM Exchange_rate
+             WHERE currency_id = $1 AND quote_currency = $2 AND recorded_at >= $3 AND recorded_at < $4
+             ORDER BY recorded_at, id LIMIT 1),
+            (SELECT price FROM Exchange_rate
+             WHERE currency_id = $1 AND quote_currency = $2 AND recorded_at >= $3 AND recorded_at < $4
+             ORDER BY recorded_at DESC, id DESC LIMIT 1)`,
+		currencyID, quoteOrDefault(quote), dayStart, dayEnd).Scan(&open, &last)
+	if err != nil {
+		return 0, err
+	}
+
+	// Сегодня курсов ещё нет
+	if !last.Valid {
+		return 0, nil
+	}
+	return percentChange(open, last.Decimal), nil
+}
+
 // GetHourlyChange возвращает изменение цены за последний час в процентах
-func (r *Repository) GetHourlyChange(currencyID int) (change float64, err error) {
+func (r *Repository) GetHourlyChange(ctx context.Context, currencyID int, quote string) (change float64, err error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	quote = quoteOrDefault(quote)
+
 	// Текущая цена
-	var currentPrice float64
-	err = r.db.QueryRow(`
+	var currentPrice models.Decimal
+	err = r.db.QueryRowContext(ctx, `
         SELECT price
         FROM Exchange_rate
-        WHERE currency_id = $1
+        WHERE currency_id = $1 AND quote_currency = $2
         ORDER BY recorded_at DESC
-        LIMIT 1`, currencyID).Scan(&currentPrice)
+        LIMIT 1`, currencyID, quote).Scan(&currentPrice)
 	if err != nil {
 		return 0, err
 	}
 
 	// Цена час назад
-	var priceHourAgo float64
-	err = r.db.QueryRow(`
+	var priceHourAgo models.Decimal
+	err = r.db.QueryRowContext(ctx, `
         SELECT price
         FROM Exchange_rate
-        WHERE currency_id = $1
+        WHERE currency_id = $1 AND quote_currency = $2
         AND recorded_at <= NOW() - INTERVAL '1 hour'
         ORDER BY recorded_at DESC
-        LIMIT 1`, currencyID).Scan(&priceHourAgo)
+        LIMIT 1`, currencyID, quote).Scan(&priceHourAgo)
 	if err != nil {
 		// Если нет записи час назад, возвращаем 0
 		return 0, nil
 	}
 
-	if priceHourAgo == 0 {
+	if priceHourAgo.IsZero() {
 		return 0, nil
 	}
 
-	change = (currentPrice - priceHourAgo) / priceHourAgo * 100
+	change = currentPrice.Sub(priceHourAgo).Float64() / priceHourAgo.Float64() * 100
 	return change, nil
 }
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// pruneBatchSize число сырых курсов, удаляемых одним запросом
+const pruneBatchSize = 10000
+
+// rollupTier таблица агрегатов и шаг её записей
+type rollupTier struct {
+	table string
+	step  time.Duration
+}
+
+var (
+	hourlyTier = rollupTier{table: "Exchange_rate_hourly", step: time.Hour}
+	dailyTier  = rollupTier{table: "Exchange_rate_daily", step: 24 * time.Hour}
+)
+
+// prunedBeforeSQL граница удалённых сырых курсов (см. PruneRawRates).
+// Раньше неё курсы читаются из агрегатов, начиная с неё - из Exchange_rate.
+const prunedBeforeSQL = `(SELECT COALESCE(MAX(pruned_before), '-infinity') FROM Exchange_rate_retention)`
+
+// tierFor возвращает таблицу агрегатов, из которой можно собрать свечи
+// интервала interval, или false, если интервал меньше часа или не кратен ему
+func tierFor(interval time.Duration) (rollupTier, bool) {
+	switch {
+	case interval%dailyTier.step == 0:
+		return dailyTier, true
+	case interval%hourlyTier.step == 0:
+		return hourlyTier, true
+	default:
+		return rollupTier{}, false
+	}
+}
+
+// RefreshRollups пересчитывает часовые и дневные агрегаты по курсам,
+// записанным начиная с часа since. Нулевой since продолжает с последнего
+// агрегированного часа, а при пустых агрегатах - с самого раннего курса.
+// Каждые сутки пересчитываются в своей транзакции со своим ограничением времени.
+// Возвращает число обновлённых часовых агрегатов.
+func (r *Repository) RefreshRollups(ctx context.Context, since time.Time) (int64, error) {
+	if since.IsZero() {
+		start, err := r.rollupStart(ctx)
+		if err != nil || !start.Valid {
+			return 0, err
+		}
+		since = start.Time
+	}
+	since = since.UTC().Truncate(time.Hour)
+
+	var updated int64
+	now := time.Now().UTC()
+	for day := since.Truncate(24 * time.Hour); !day.After(now); day = day.Add(24 * time.Hour) {
+		from := day
+		if since.After(from) {
+			from = since
+		}
+		hours, err := r.refreshRollupDay(ctx, from, day.Add(24*time.Hour))
+		if err != nil {
+			return updated, err
+		}
+		updated += hours
+	}
+	return updated, nil
+}
+
+// rollupStart возвращает последний агрегированный час, он мог быть неполным,
+// или время самого раннего курса, если агрегатов ещё нет
+func (r *Repository) rollupStart(ctx context.Context) (sql.NullTime, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var start sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COALESCE(
+            (SELECT MAX(bucket) FROM Exchange_rate_hourly),
+            (SELECT MIN(recorded_at) FROM Exchange_rate)
+        )`).Scan(&start)
+	return start, err
+}
+
+// refreshRollupDay пересчитывает часы [from, to) по сырым курсам
+// и сутки, в которые попадает from, по часовым агрегатам.
+// Агрегаты часов раньше границы удаления сырых курсов не перезаписываются:
+// от их курсов остались только загруженные позже, например через backfill.
+// Часы без агрегата добавляются как обычно.
+func (r *Repository) refreshRollupDay(ctx context.Context, from, to time.Time) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+        INSERT INTO Exchange_rate_hourly (currency_id, quote_currency, bucket, open, high, low, close, samples)
+        SELECT currency_id, quote_currency, date_trunc('hour', recorded_at, 'UTC') AS hour,
+            (array_agg(price ORDER BY recorded_at, id))[1],
+            MAX(price),
+            MIN(price),
+            (array_agg(price ORDER BY recorded_at DESC, id DESC))[1],
+            COUNT(*)
+        FROM Exchange_rate
+        WHERE recorded_at >= $1 AND recorded_at < $2
+        GROUP BY currency_id, quote_currency, hour
+        ON CONFLICT (currency_id, quote_currency, bucket) DO UPDATE
+        SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+            close = EXCLUDED.close, samples = EXCLUDED.samples
+        WHERE Exchange_rate_hourly.bucket >= `+prunedBeforeSQL, from, to)
+	if err != nil {
+		return 0, err
+	}
+	hours, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO Exchange_rate_daily (currency_id, quote_currency, bucket, open, high, low, close, samples)
+        SELECT currency_id, quote_currency, date_trunc('day', bucket, 'UTC') AS day,
+            (array_agg(open ORDER BY bucket))[1],
+            MAX(high),
+            MIN(low),
+            (array_agg(close ORDER BY bucket DESC))[1],
+            SUM(samples)
+        FROM Exchange_rate_hourly
+        WHERE bucket >= date_trunc('day', $1::timestamptz, 'UTC') AND bucket < $2
+        GROUP BY currency_id, quote_currency, day
+        ON CONFLICT (currency_id, quote_currency, bucket) DO UPDATE
+        SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+            close = EXCLUDED.close, samples = EXCLUDED.samples`, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return hours, tx.Commit()
+}
+
+// PruneRawRates удаляет сырые курсы старше before. Удаляются только целые
+// часы, уже вошедшие в агрегаты, поэтому история и свечи за этот период
+// остаются доступны. Удаление идёт пачками, каждая со своим ограничением времени.
+// Граница удаления сохраняется до первой пачки, чтобы RefreshRollups
+// не пересчитывал агрегаты часов, курсы которых уже удалены.
+// Возвращает число удалённых курсов.
+func (r *Repository) PruneRawRates(ctx context.Context, before time.Time) (int64, error) {
+	before = before.UTC().Truncate(time.Hour)
+	if err := r.savePruneWatermark(ctx, before); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for {
+		batchCtx, cancel := r.withTimeout(ctx)
+		result, err := r.db.ExecContext(batchCtx, `
+        DELETE FROM Exchange_rate
+        WHERE id IN (
+            SELECT id FROM Exchange_rate
+            WHERE recorded_at < LEAST($1, (SELECT COALESCE(MAX(bucket), '-infinity') FROM Exchange_rate_hourly))
+            LIMIT $2
+        )`, before, pruneBatchSize)
+		cancel()
+		if err != nil {
+			return deleted, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += affected
+		if affected < pruneBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// savePruneWatermark сдвигает границу удалённых сырых курсов до before,
+// но не дальше последнего агрегированного часа. Граница не уменьшается.
+func (r *Repository) savePruneWatermark(ctx context.Context, before time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO Exchange_rate_retention (pruned_before)
+        SELECT LEAST($1, MAX(bucket)) FROM Exchange_rate_hourly
+        HAVING MAX(bucket) IS NOT NULL
+        ON CONFLICT (id) DO UPDATE
+        SET pruned_before = GREATEST(Exchange_rate_retention.pruned_before, EXCLUDED.pruned_before)`, before)
+	return err
+}
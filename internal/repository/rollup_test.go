@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepository_RefreshRollups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Продолжение с последнего агрегированного часа: пересчитываются сутки,
+	// в которые он попадает, и каждые следующие до текущих
+	since := time.Now().UTC().Truncate(time.Hour).Add(-24 * time.Hour)
+	mock.ExpectQuery(`SELECT COALESCE\(\s*\(SELECT MAX\(bucket\) FROM Exchange_rate_hourly\),\s*\(SELECT MIN\(recorded_at\) FROM Exchange_rate\)\s*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(since.Add(10 * time.Minute)))
+
+	day := since.Truncate(24 * time.Hour)
+	from := since
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO Exchange_rate_hourly .+ FROM Exchange_rate WHERE recorded_at >= \$1 AND recorded_at < \$2 GROUP BY currency_id, quote_currency, hour ON CONFLICT .+ WHERE Exchange_rate_hourly.bucket >= \(SELECT COALESCE\(MAX\(pruned_before\), '-infinity'\) FROM Exchange_rate_retention\)`).
+			WithArgs(from, day.Add(24*time.Hour)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`INSERT INTO Exchange_rate_daily .+ FROM Exchange_rate_hourly WHERE bucket >= date_trunc\('day', \$1::timestamptz, 'UTC'\) AND bucket < \$2`).
+			WithArgs(from, day.Add(24*time.Hour)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		day = day.Add(24 * time.Hour)
+		from = day
+	}
+
+	updated, err := repo.RefreshRollups(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatalf("RefreshRollups failed: %v", err)
+	}
+	if updated != 6 {
+		t.Errorf("Expected 6 hourly rollups updated, got %d", updated)
+	}
+
+	// Без курсов пересчитывать нечего
+	mock.ExpectQuery(`SELECT COALESCE`).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(nil))
+
+	updated, err = repo.RefreshRollups(context.Background(), time.Time{})
+	if err != nil || updated != 0 {
+		t.Errorf("Expected nothing to refresh, got %d, %v", updated, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRepository_RefreshRollups_KeepsPrunedHours(t *testing.T) {
+	db := openTestDB(t)
+	truncateTestDB(t, db)
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	currency := mustCreateCurrency(t, repo, "bitcoin", "BTC")
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+	save := func(points ...models.PricePoint) {
+		t.Helper()
+		if _, err := repo.SaveHistoricalRates(ctx, currency.ID, "usd", "test", points); err != nil {
+			t.Fatalf("SaveHistoricalRates failed: %v", err)
+		}
+	}
+	price := func(value string) models.Decimal {
+		d, err := models.ParseDecimal(value)
+		if err != nil {
+			t.Fatalf("ParseDecimal failed: %v", err)
+		}
+		return d
+	}
+
+	save(
+		models.PricePoint{Time: hour, Price: price("100")},
+		models.PricePoint{Time: hour.Add(20 * time.Minute), Price: price("120")},
+		models.PricePoint{Time: hour.Add(40 * time.Minute), Price: price("90")},
+		models.PricePoint{Time: hour.Add(time.Hour), Price: price("110")},
+	)
+	if _, err := repo.RefreshRollups(ctx, hour); err != nil {
+		t.Fatalf("RefreshRollups failed: %v", err)
+	}
+	if deleted, err := repo.PruneRawRates(ctx, hour.Add(time.Hour)); err != nil || deleted != 3 {
+		t.Fatalf("Expected 3 rates pruned, got %d, %v", deleted, err)
+	}
+
+	// Backfill в удалённый час и в час без агрегата раньше границы
+	save(
+		models.PricePoint{Time: hour.Add(30 * time.Minute), Price: price("200")},
+		models.PricePoint{Time: hour.Add(-time.Hour), Price: price("50")},
+	)
+	if _, err := repo.RefreshRollups(ctx, hour.Add(-time.Hour)); err != nil {
+		t.Fatalf("RefreshRollups after backfill failed: %v", err)
+	}
+
+	tests := []struct {
+		bucket                 time.Time
+		open, high, low, close string
+		samples                int
+	}{
+		{hour.Add(-time.Hour), "50", "50", "50", "50", 1},
+		{hour, "100", "120", "90", "90", 3},
+		{hour.Add(time.Hour), "110", "110", "110", "110", 1},
+	}
+	for _, tt := range tests {
+		var open, high, low, close models.Decimal
+		var samples int
+		err := db.QueryRow(`
+            SELECT open, high, low, close, samples FROM Exchange_rate_hourly
+            WHERE currency_id = $1 AND quote_currency = 'usd' AND bucket = $2`,
+			currency.ID, tt.bucket).Scan(&open, &high, &low, &close, &samples)
+		if err != nil {
+			t.Fatalf("Failed to read rollup %v: %v", tt.bucket, err)
+		}
+		if !open.Equal(price(tt.open)) || !high.Equal(price(tt.high)) || !low.Equal(price(tt.low)) ||
+			!close.Equal(price(tt.close)) || samples != tt.samples {
+			t.Errorf("Rollup %v: got %s/%s/%s/%s (%d), expected %s/%s/%s/%s (%d)", tt.bucket,
+				open, high, low, close, samples, tt.open, tt.high, tt.low, tt.close, tt.samples)
+		}
+	}
+
+	// До границы удаления история читается из агрегатов, backfill в неё не виден
+	history, err := repo.GetRateHistory(ctx, models.HistoryQuery{
+		CurrencyID: currency.ID, From: hour.Add(-time.Hour), To: hour.Add(2 * time.Hour), Limit: 10,
+	})
+	if err != nil {
+		t.Fatalf("GetRateHistory failed: %v", err)
+	}
+	expected := []struct {
+		raw   bool
+		price string
+	}{{false, "50"}, {false, "100"}, {true, "110"}}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d history points, got %+v", len(expected), history)
+	}
+	for i, point := range history {
+		if (point.ID != 0) != expected[i].raw || !point.Price.Equal(price(expected[i].price)) {
+			t.Errorf("History point %d: got %+v, expected %s", i, point, expected[i].price)
+		}
+	}
+}
+
+func TestRepository_PruneRawRates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Граница округляется до часа и сохраняется до удаления,
+	// удаление идёт пачками до неполной пачки
+	before := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO Exchange_rate_retention \(pruned_before\) SELECT LEAST\(\$1, MAX\(bucket\)\) FROM Exchange_rate_hourly .+ SET pruned_before = GREATEST\(`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	query := `DELETE FROM Exchange_rate WHERE id IN \( SELECT id FROM Exchange_rate WHERE recorded_at < LEAST\(\$1, \(SELECT COALESCE\(MAX\(bucket\), '-infinity'\) FROM Exchange_rate_hourly\)\) LIMIT \$2 \)`
+	mock.ExpectExec(query).
+		WithArgs(before, pruneBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, pruneBatchSize))
+	mock.ExpectExec(query).
+		WithArgs(before, pruneBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, 15))
+
+	deleted, err := repo.PruneRawRates(context.Background(), before.Add(25*time.Minute))
+	if err != nil {
+		t.Fatalf("PruneRawRates failed: %v", err)
+	}
+	if deleted != pruneBatchSize+15 {
+		t.Errorf("Expected %d rates deleted, got %d", pruneBatchSize+15, deleted)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTierFor(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		table    string
+	}{
+		{5 * time.Minute, ""},
+		{90 * time.Minute, ""},
+		{time.Hour, "Exchange_rate_hourly"},
+		{4 * time.Hour, "Exchange_rate_hourly"},
+		{24 * time.Hour, "Exchange_rate_daily"},
+		{7 * 24 * time.Hour, "Exchange_rate_daily"},
+	}
+	for _, tt := range tests {
+		tier, ok := tierFor(tt.interval)
+		if ok != (tt.table != "") || tier.table != tt.table {
+			t.Errorf("tierFor(%v) = %q, %v; expected %q", tt.interval, tier.table, ok, tt.table)
+		}
+	}
+}
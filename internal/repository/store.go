@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"time"
+)
+
+// Store контракт хранилища сервисов: валюты, курсы, пользователи бота
+// и журнал загрузки. Реализуется Repository (PostgreSQL) и MemoryRepository
+// (память процесса, для тестов и демо-режима). Поведение реализаций
+// проверяется общим набором тестов в conformance_test.go.
+//
+// Обслуживание хранения - advisory-блокировки, партиции, агрегаты
+// и удаление сырых курсов - есть только у Repository.
+type Store interface {
+	Ping(ctx context.Context) error
+
+	// Курсы
+	SaveRate(ctx context.Context, rate models.ExchangeRate) error
+	SaveRates(ctx context.Context, rates []models.ExchangeRate) (time.Time, error)
+	SaveHistoricalRates(ctx context.Context, currencyID int, quote, source string, points []models.PricePoint) (int64, error)
+	GetLatestRates(ctx context.Context, quote string) ([]models.CurrencyRateView, error)
+	GetCurrencyRate(ctx context.Context, currencyID int, quote string) (models.ExchangeRate, error)
+	GetRateSummaries(ctx context.Context, quote string, loc *time.Location, currencyIDs ...int) ([]models.RateSummary, error)
+	GetRateHistory(ctx context.Context, query models.HistoryQuery) ([]models.HistoryPoint, error)
+	GetCandles(ctx context.Context, query models.CandleQuery) ([]models.Candle, error)
+	GetDailyMinMax(ctx context.Context, currencyID int, quote string, loc *time.Location) (min, max models.Decimal, err error)
+	GetDayChange(ctx context.Context, currencyID int, quote string, loc *time.Location) (float64, error)
+	GetHourlyChange(ctx context.Context, currencyID int, quote string) (float64, error)
+
+	// Загрузка курсов
+	ConsumeRequestBudget(ctx context.Context, provider string, windowStart time.Time, limit int) (bool, error)
+	StartFetchRun(ctx context.Context, provider string, startedAt time.Time) (int, error)
+	FinishFetchRun(ctx context.Context, run models.FetchRun) error
+	GetFetchRuns(ctx context.Context, limit int) ([]models.FetchRun, error)
+	GetLastSuccessfulFetch(ctx context.Context) (time.Time, error)
+
+	// Валюты
+	GetCurrencyID(ctx context.Context, name string) (int, error)
+	GetCurrencyIDBySymbol(ctx context.Context, symbol string) (int, error)
+	GetCurrencySymbol(ctx context.Context, currencyID int) (string, error)
+	GetCurrencySymbolByID(ctx context.Context, currencyID int) (string, error)
+	GetCurrencyDisplayName(ctx context.Context, currencyID int) (string, error)
+	GetAllCurrencies(ctx context.Context) ([]models.Currency, error)
+	GetCurrencyByID(ctx context.Context, id int) (models.Currency, error)
+	GetTrackedCurrencies(ctx context.Context) ([]models.Currency, error)
+	GetTrackedFreshness(ctx context.Context, quote string) ([]models.CurrencyFreshness, error)
+	CreateCurrency(ctx context.Context, currency models.Currency) (models.Currency, error)
+	UpdateCurrency(ctx context.Context, id int, update models.CurrencyUpdate) (models.Currency, error)
+	DeleteCurrency(ctx context.Context, id int) error
+
+	// Пользователи бота
+	EnsureUser(ctx context.Context, userID int64, userName string) error
+	SetUserInterval(ctx context.Context, userID int64, interval int) error
+	StopAuto(ctx context.Context, userID int64) error
+	GetSubscribedUsers(ctx context.Context) ([]models.UserSettings, error)
+	SetUserTimezone(ctx context.Context, userID int64, timezone string) error
+	GetUserTimezone(ctx context.Context, userID int64) (string, error)
+	UpdateLastSent(ctx context.Context, userID int64) error
+}
+
+var (
+	_ Store = (*Repository)(nil)
+	_ Store = (*MemoryRepository)(nil)
+)
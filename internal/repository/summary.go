@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"cryptorate-service/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetRateSummaries возвращает последний курс, описание валюты, дневные
+// минимум и максимум и изменения за час, день и 24 часа одним запросом.
+// Без currencyIDs возвращаются все валюты, у которых есть курс в котировке quote.
+// День - текущий календарный день в часовом поясе loc.
+func (r *Repository) GetRateSummaries(ctx context.Context, quote string, loc *time.Location, currencyIDs ...int) ([]models.RateSummary, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	// Каждый LATERAL читает курсы одной валюты по индексу
+	// idx_exchange_rate_currency_quote_time, без сортировки всей таблицы
+	query := `
+        SELECT
+            c.id,
+            c.name_currency,
+            COALESCE(c.symbol, ''),
+            COALESCE(c.display_name, ''),
+            c.max_age_seconds,
+            e.price,
+            e.recorded_at,
+            e.market_cap,
+            e.volume_24h,
+            e.change_24h,
+            d.min_price,
+            d.max_price,
+            d.open_price,
+            h.price,
+            p.price
+        FROM currency c
+        CROSS JOIN LATERAL (
+            SELECT price, recorded_at, market_cap, volume_24h, change_24h
+            FROM exchange_rate
+            WHERE currency_id = c.id AND quote_currency = $1
+            ORDER BY recorded_at DESC, id DESC
+            LIMIT 1
+        ) e
+        CROSS JOIN LATERAL (
+            SELECT MIN(price) AS min_price, MAX(price) AS max_price,
+                (array_agg(price ORDER BY recorded_at, id))[1] AS open_price
+            FROM exchange_rate
+            WHERE currency_id = c.id AND quote_currency = $1
+            AND recorded_at >= $2 AND recorded_at < $3
+        ) d
+        LEFT JOIN LATERAL (
+            SELECT price
+            FROM exchange_rate
+            WHERE currency_id = c.id AND quote_currency = $1 AND recorded_at <= $4
+            ORDER BY recorded_at DESC, id DESC
+            LIMIT 1
+        ) h ON TRUE
+        LEFT JOIN LATERAL (
+            SELECT price
+            FROM exchange_rate
+            WHERE currency_id = c.id AND quote_currency = $1 AND recorded_at <= $5
+            ORDER BY recorded_at DESC, id DESC
+            LIMIT 1
+        ) p ON TRUE
+        WHERE c.deleted_at IS NULL
+        AND ($6::int[] IS NULL OR c.id = ANY($6))
+        ORDER BY c.name_currency`
+
+	var ids pq.Int64Array
+	for _, id := range currencyIDs {
+		ids = append(ids, int64(id))
+	}
+
+	quote = quoteOrDefault(quote)
+	now := time.Now()
+	dayStart, dayEnd := models.CalendarDay(now, loc)
+	rows, err := r.db.QueryContext(ctx, query, quote, dayStart, dayEnd,
+		now.Add(-time.Hour), now.Add(-24*time.Hour), ids)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.RateSummary
+	for rows.Next() {
+		summary := models.RateSummary{Quote: quote}
+		var maxAge sql.NullInt64
+		var marketCap, volume, change sql.NullFloat64
+		var dayMin, dayMax, dayOpen, hourAgo, dayAgo models.NullDecimal
+		err := rows.Scan(&summary.CurrencyID, &summary.NameCurrency, &summary.Symbol, &summary.DisplayName,
+			&maxAge, &summary.Price, &summary.RecordedAt, &marketCap, &volume, &change,
+			&dayMin, &dayMax, &dayOpen, &hourAgo, &dayAgo)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		summary.MaxAge = maxAgeFromNull(maxAge)
+		summary.Market = marketFromNull(marketCap, volume, change)
+		summary.DailyMin = dayMin.Decimal
+		summary.DailyMax = dayMax.Decimal
+		// Курсы за сегодня есть, значит последний курс тоже сегодняшний
+		summary.DayChange = percentChange(dayOpen, summary.Price)
+		summary.HourlyChange = percentChange(hourAgo, summary.Price)
+		summary.Change24h = percentChange(dayAgo, summary.Price)
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// percentChange возвращает изменение цены от from до to в процентах,
+// ноль - если начальной цены нет
+func percentChange(from models.NullDecimal, to models.Decimal) float64 {
+	if !from.Valid || from.Decimal.IsZero() {
+		return 0
+	}
+	return to.Sub(from.Decimal).Float64() / from.Decimal.Float64() * 100
+}
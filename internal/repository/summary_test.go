@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestRepository_GetRateSummaries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	columns := []string{"id", "name_currency", "symbol", "display_name", "max_age_seconds",
+		"price", "recorded_at", "market_cap", "volume_24h", "change_24h",
+		"min_price", "max_price", "open_price", "price", "price"}
+	recordedAt := time.Now().Add(-time.Minute)
+
+	// Все валюты одним запросом, для второй нет курсов за сегодня и час назад
+	mock.ExpectQuery(`SELECT c\.id, c\.name_currency, COALESCE\(c\.symbol, ''\), COALESCE\(c\.display_name, ''\).*`+
+		`FROM currency c CROSS JOIN LATERAL .* LEFT JOIN LATERAL .* LEFT JOIN LATERAL .*`+
+		`WHERE c\.deleted_at IS NULL AND \(\$6::int\[\] IS NULL OR c\.id = ANY\(\$6\)\) ORDER BY c\.name_currency`).
+		WithArgs("eur", midnightArg{time.UTC}, midnightArg{time.UTC}, sqlmock.AnyArg(), sqlmock.AnyArg(), pq.Int64Array(nil)).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "bitcoin", "BTC", "Bitcoin", nil,
+				[]byte("41000.50"), recordedAt, 810000000000.0, nil, -1.25,
+				[]byte("40000"), []byte("41500"), []byte("40000"), []byte("41000"), []byte("50000")).
+			AddRow(2, "shiba-inu", "SHIB", "Shiba Inu", 600,
+				[]byte("0.000012"), recordedAt, nil, nil, nil,
+				nil, nil, nil, nil, []byte("0.00001"),
+			))
+
+	summaries, err := repo.GetRateSummaries(context.Background(), "EUR", time.UTC)
+	if err != nil {
+		t.Fatalf("GetRateSummaries failed: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summaries, got %d", len(summaries))
+	}
+
+	btc := summaries[0]
+	if btc.Symbol != "BTC" || btc.DisplayName != "Bitcoin" || btc.Quote != "eur" || btc.Price.String() != "41000.5" {
+		t.Errorf("Unexpected bitcoin summary: %+v", btc)
+	}
+	if btc.DailyMin.String() != "40000" || btc.DailyMax.String() != "41500" {
+		t.Errorf("Unexpected daily range: %s - %s", btc.DailyMin, btc.DailyMax)
+	}
+	if math.Abs(btc.DayChange-2.50125) > 1e-9 || math.Abs(btc.HourlyChange-0.00121951) > 1e-6 ||
+		math.Abs(btc.Change24h+17.999) > 1e-9 {
+		t.Errorf("Unexpected changes: day %v, hour %v, 24h %v", btc.DayChange, btc.HourlyChange, btc.Change24h)
+	}
+	if btc.Market == nil || btc.Market.Change24h != -1.25 {
+		t.Errorf("Unexpected market data: %+v", btc.Market)
+	}
+
+	shib := summaries[1]
+	if !shib.DailyMin.IsZero() || shib.DayChange != 0 || shib.HourlyChange != 0 || shib.MaxAge != 10*time.Minute {
+		t.Errorf("Unexpected summary without today's rates: %+v", shib)
+	}
+	if math.Abs(shib.Change24h-20) > 1e-9 {
+		t.Errorf("Expected 24h change 20%%, got %v", shib.Change24h)
+	}
+
+	// Выбранные валюты передаются массивом
+	mock.ExpectQuery(`SELECT c\.id`).
+		WithArgs("usd", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), pq.Int64Array{2}).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	summaries, err = repo.GetRateSummaries(context.Background(), "", time.UTC, 2)
+	if err != nil {
+		t.Fatalf("GetRateSummaries failed: %v", err)
+	}
+	if len(summaries) != 0 {
+		t.Errorf("Expected no summaries, got %+v", summaries)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}